@@ -0,0 +1,172 @@
+package carbone
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// APIFeatures are the features of Carbone Render available with the API version of a CSDK, see Features.
+type APIFeatures struct {
+	// Version is the API version sent in the "carbone-version" header
+	Version int
+	// VersionedTemplates reports whether an upload can add a version to a template: the template keeps a stable ID
+	// and each upload returns a VersionID
+	VersionedTemplates bool
+	// TemplateMetadata reports whether the templates have a name, a comment, tags and a category, and can be listed
+	TemplateMetadata bool
+	// DirectDownload reports whether a render can return the report in the response, without a download request
+	DirectDownload bool
+	// InlineTemplates reports whether a template can be sent with the render request, without being stored
+	InlineTemplates bool
+}
+
+// apiDialect describes the endpoints and the response shapes of a version of the Carbone API.
+type apiDialect struct {
+	features APIFeatures
+}
+
+// dialectOf returns the dialect of an API version. The versions before 5 share the behaviour of the version 4,
+// the later versions the behaviour of the version 5.
+func dialectOf(version string) apiDialect {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		v = defaultAPIVersion
+	}
+	features := APIFeatures{Version: v}
+	if v >= 5 {
+		features.VersionedTemplates = true
+		features.TemplateMetadata = true
+		features.DirectDownload = true
+		features.InlineTemplates = true
+	}
+	return apiDialect{features: features}
+}
+
+// Features returns the features of Carbone Render available with the API version set by SetAPIVersion.
+func (csdk *CSDK) Features() APIFeatures {
+	return csdk.dialect().features
+}
+
+// dialect returns the dialect of the API version of the CSDK.
+func (csdk *CSDK) dialect() apiDialect {
+	return dialectOf(csdk.settings().apiVersion)
+}
+
+// require returns ErrUnsupported if the feature named name is not available.
+func (d apiDialect) require(op string, name string, available bool) error {
+	if available {
+		return nil
+	}
+	return fmt.Errorf("Carbone SDK %s error: %w: %s requires the API version 5, the version is %d", op, ErrUnsupported, name, d.features.Version)
+}
+
+// uploadURL returns the endpoint of the template uploads.
+func (d apiDialect) uploadURL(baseURL string) string {
+	return baseURL + "/template"
+}
+
+// templateURL returns the endpoint of a template. With the version 5, templateID is a template ID or a version ID.
+func (d apiDialect) templateURL(baseURL string, templateID string) string {
+	return baseURL + "/template/" + url.PathEscape(templateID)
+}
+
+// renderURL returns the endpoint rendering a template. download asks for the report in the response.
+func (d apiDialect) renderURL(baseURL string, templateID string, download bool) string {
+	u := baseURL + "/render/" + url.PathEscape(templateID)
+	if download && d.features.DirectDownload {
+		u += "?download=true"
+	}
+	return u
+}
+
+// inlineRenderURL returns the endpoint rendering a template sent in the body of the request.
+func (d apiDialect) inlineRenderURL(baseURL string, download bool) string {
+	return d.renderURL(baseURL, "template", download)
+}
+
+// reportURL returns the endpoint downloading a report.
+func (d apiDialect) reportURL(baseURL string, renderID string) string {
+	return baseURL + "/render/" + url.PathEscape(renderID)
+}
+
+// uploadFields returns the form fields of a template upload, the template is sent after them.
+func (d apiDialect) uploadFields(op string, opts AddTemplateOptions) ([]formField, error) {
+	fields := []formField{{"payload", opts.Payload}}
+	if opts.Versioning {
+		if err := d.require(op, "versioning", d.features.VersionedTemplates); err != nil {
+			return nil, err
+		}
+		fields = append(fields, formField{"versioning", "true"})
+	}
+	if opts.TemplateID != "" {
+		if err := d.require(op, "versioning", d.features.VersionedTemplates); err != nil {
+			return nil, err
+		}
+		if !opts.Versioning {
+			fields = append(fields, formField{"versioning", "true"})
+		}
+		fields = append(fields, formField{"id", opts.TemplateID})
+	}
+	if opts.Name != "" || opts.Comment != "" || len(opts.Tags) > 0 || opts.Category != "" {
+		if err := d.require(op, "template metadata", d.features.TemplateMetadata); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []formField{{"name", opts.Name}, {"comment", opts.Comment}, {"category", opts.Category}} {
+		if f.value != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(opts.Tags) > 0 {
+		// The tags are sent as a JSON array
+		tags, err := json.Marshal(opts.Tags)
+		if err != nil {
+			return nil, &MarshalError{Err: err}
+		}
+		fields = append(fields, formField{"tags", string(tags)})
+	}
+	return fields, nil
+}
+
+// templatesURL returns the endpoint listing the templates.
+func (d apiDialect) templatesURL(baseURL string, query url.Values) string {
+	u := baseURL + "/templates"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// decodeResponse parses the JSON response of a request. The version 5 returns the template ID and the extension
+// of an upload in the "id" and "type" fields, they are copied to TemplateID and TemplateFileExtension.
+func (d apiDialect) decodeResponse(body []byte, cResp *APIResponse) error {
+	if err := json.Unmarshal(body, cResp); err != nil {
+		return err
+	}
+	if d.features.Version < 5 {
+		return nil
+	}
+	v5 := struct {
+		Data struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"data"`
+	}{}
+	if json.Unmarshal(body, &v5) == nil {
+		if cResp.Data.TemplateID == "" {
+			cResp.Data.TemplateID = v5.Data.ID
+		}
+		if cResp.Data.TemplateFileExtension == "" {
+			cResp.Data.TemplateFileExtension = v5.Data.Type
+		}
+	}
+	return nil
+}
+
+// formField is a field of a multipart form.
+type formField struct {
+	name  string
+	value string
+}
@@ -0,0 +1,123 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerVersionedResponders mocks Carbone Render answering with the response shapes of the API version.
+func registerVersionedResponders(version int) {
+	register := func(method, path string, answer func(req *http.Request) *http.Response) {
+		httpmock.RegisterResponder(method, "https://api.carbone.io"+path, func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("carbone-version") != strconv.Itoa(version) {
+				return httpmock.NewStringResponse(http.StatusBadRequest, `{"success": false, "error": "unexpected carbone-version"}`), nil
+			}
+			return answer(req), nil
+		})
+	}
+	register("POST", "/template", func(req *http.Request) *http.Response {
+		if _, _, err := req.FormFile("template"); err != nil {
+			return httpmock.NewStringResponse(http.StatusBadRequest, "")
+		}
+		if version < 5 {
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"templateId": "tpl", "inputFileExtension": "html"}}`)
+		}
+		versionID := "ver-1"
+		if req.FormValue("versioning") == "true" {
+			versionID = "ver-2"
+		}
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"id": "tpl", "versionId": "`+versionID+`", "type": "html"}}`)
+	})
+	register("POST", "/render/tpl", func(req *http.Request) *http.Response {
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "report"}}`)
+	})
+	register("GET", "/render/report", func(req *http.Request) *http.Response {
+		return httpmock.NewStringResponse(200, "report v"+strconv.Itoa(version))
+	})
+	register("DELETE", "/template/tpl", func(req *http.Request) *http.Response {
+		return httpmock.NewStringResponse(200, `{"success": true}`)
+	})
+}
+
+func TestAPIVersions(t *testing.T) {
+	versions := []struct {
+		version   int
+		versionID string
+		features  APIFeatures
+	}{
+		{4, "", APIFeatures{Version: 4}},
+		{5, "ver-1", APIFeatures{Version: 5, VersionedTemplates: true, TemplateMetadata: true, DirectDownload: true, InlineTemplates: true}},
+	}
+	for _, v := range versions {
+		v := v
+		t.Run("API version "+strconv.Itoa(v.version), func(t *testing.T) {
+			// ---- httpmock
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+			registerVersionedResponders(v.version)
+			// ----
+			c, err := New(WithToken("token"), WithAPIVersion(v.version))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Run("Should report the features of the version", func(t *testing.T) {
+				if c.Features() != v.features {
+					t.Error(errors.New("The features are not correct"), c.Features())
+				}
+			})
+
+			t.Run("Should read the templateID of an upload", func(t *testing.T) {
+				resp, err := c.AddTemplate("./tests/template.test.html")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !resp.Success || resp.Data.TemplateID != "tpl" || resp.Data.VersionID != v.versionID {
+					t.Error(errors.New("The upload response is not correct"), resp)
+				}
+			})
+
+			t.Run("Should render, download and delete", func(t *testing.T) {
+				report, err := c.Render("tpl", `{"data":{}}`)
+				if err != nil || string(report) != "report v"+strconv.Itoa(v.version) {
+					t.Fatal(errors.New("The report is not correct"), err)
+				}
+				if resp, err := c.DeleteTemplate("tpl"); err != nil || !resp.Success {
+					t.Error(errors.New("The template should have been deleted"), err)
+				}
+			})
+
+			t.Run("Should upload a new version of a template only if the version supports it", func(t *testing.T) {
+				before := httpmock.GetTotalCallCount()
+				resp, err := c.AddTemplateFromReader(context.Background(), "template.html", strings.NewReader("<html></html>"), AddTemplateOptions{Versioning: true})
+				if !v.features.VersionedTemplates {
+					if !errors.Is(err, ErrUnsupported) || httpmock.GetTotalCallCount() != before {
+						t.Error(errors.New("The upload should have been rejected before any request"), err)
+					}
+					return
+				}
+				if err != nil || resp.Data.TemplateID != "tpl" || resp.Data.VersionID != "ver-2" {
+					t.Error(errors.New("The versioned upload is not correct"), err, resp)
+				}
+			})
+		})
+	}
+
+	t.Run("Should build the endpoints of each version", func(t *testing.T) {
+		if u := dialectOf("4").renderURL("https://api.carbone.io", "tpl", true); u != "https://api.carbone.io/render/tpl" {
+			t.Error(errors.New("The version 4 does not support the direct download"), u)
+		}
+		if u := dialectOf("5").renderURL("https://api.carbone.io", "tpl", true); u != "https://api.carbone.io/render/tpl?download=true" {
+			t.Error(errors.New("The version 5 should download the report directly"), u)
+		}
+		if u := dialectOf("5").templateURL("https://api.carbone.io", "a/b"); u != "https://api.carbone.io/template/a%2Fb" {
+			t.Error(errors.New("The templateID should be escaped"), u)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package carbone
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+)
+
+// reportContentTypes are the media types of the formats generated by Carbone Render.
+var reportContentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".odg":  "application/vnd.oasis.opendocument.graphics",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".csv":  "text/csv",
+	".txt":  "text/plain",
+	".html": "text/html",
+	".xml":  "application/xml",
+	".json": "application/json",
+	".md":   "text/markdown",
+	".epub": "application/epub+zip",
+	".rtf":  "application/rtf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".svg":  "image/svg+xml",
+}
+
+// ReportArchive is the ZIP archive returned by a batch render, it contains one report per element of batchSplitBy.
+type ReportArchive struct {
+	Entries []ArchiveEntry
+}
+
+// ArchiveEntry is a report of a ReportArchive.
+type ArchiveEntry struct {
+	// Name is the path of the report in the archive, set by batchReportName
+	Name string
+	// ContentType is the media type guessed from the extension of Name
+	ContentType string
+	// Size is the uncompressed size of the report in bytes
+	Size int64
+
+	file *zip.File
+}
+
+// Open returns a reader of the report, the caller must close it.
+func (e ArchiveEntry) Open() (io.ReadCloser, error) {
+	return e.file.Open()
+}
+
+// OpenReportArchive opens the ZIP archive of a batch render, r is read on demand. size is the size of the archive in bytes.
+// Use bytes.NewReader to open an archive returned by Render, or an *os.File to open an archive written by RenderTo.
+func OpenReportArchive(r io.ReaderAt, size int64) (*ReportArchive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("Carbone SDK OpenReportArchive error: the report is not a ZIP archive: %w", err)
+	}
+	archive := &ReportArchive{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		archive.Entries = append(archive.Entries, ArchiveEntry{
+			Name:        f.Name,
+			ContentType: contentTypeOf(f.Name),
+			Size:        int64(f.UncompressedSize64),
+			file:        f,
+		})
+	}
+	return archive, nil
+}
+
+// RenderArchive render one report per element of req.BatchSplitBy, like RenderWithRequest, and opens the ZIP archive
+// returned by Carbone Render. BatchOutput is set to "zip" if it is empty.
+func (csdk *CSDK) RenderArchive(ctx context.Context, pathOrTemplateID string, req RenderRequest, args ...string) (*ReportArchive, error) {
+	if req.BatchSplitBy == "" {
+		return nil, fmt.Errorf("Carbone SDK RenderArchive error: %w: batchSplitBy", ErrMissingArgument)
+	}
+	if req.BatchOutput == "" {
+		req.BatchOutput = "zip"
+	}
+	report, err := csdk.RenderWithRequest(ctx, pathOrTemplateID, req, args...)
+	if err != nil {
+		return nil, err
+	}
+	return OpenReportArchive(bytes.NewReader(report), int64(len(report)))
+}
+
+// contentTypeOf returns the media type of a file name, "application/octet-stream" if the extension is unknown.
+func contentTypeOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := reportContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,115 @@
+package carbone
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// newZip returns a ZIP archive containing files
+func newZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReportArchive(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+
+	t.Run("Should render a batch and expose each report of the ZIP archive", func(t *testing.T) {
+		archive := newZip(t, map[string]string{"payslip-1.pdf": "John", "payslip-2.pdf": "Jane"})
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if string(body) != `{"data":{"employees":[{"name":"John"},{"name":"Jane"}]},"batchSplitBy":"d.employees","batchOutput":"zip","batchReportName":"payslip-{d.name}.pdf","convertTo":"pdf"}` {
+				return httpmock.NewStringResponse(400, `{"success": false, "error": "unexpected body"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`), nil
+		})
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewBytesResponder(200, archive))
+		// ----
+		data := map[string]interface{}{"employees": []map[string]string{{"name": "John"}, {"name": "Jane"}}}
+		opts := RenderOptions{ConvertTo: "pdf", BatchSplitBy: "d.employees", BatchReportName: "payslip-{d.name}.pdf"}
+		reports, err := csdk.RenderArchive(context.Background(), templateID, RenderRequest{Data: data, RenderOptions: opts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reports.Entries) != 2 {
+			t.Fatal(errors.New("The archive should contain 2 reports"))
+		}
+		contents := map[string]string{}
+		for _, entry := range reports.Entries {
+			if entry.ContentType != "application/pdf" || entry.Size != 4 {
+				t.Error(errors.New("The content type or the size of the entry is not correct"))
+			}
+			r, err := entry.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, _ := ioutil.ReadAll(r)
+			r.Close()
+			contents[entry.Name] = string(content)
+		}
+		if contents["payslip-1.pdf"] != "John" || contents["payslip-2.pdf"] != "Jane" {
+			t.Error(errors.New("The content of the reports is not correct"))
+		}
+	})
+
+	t.Run("Should guess the content type of the reports", func(t *testing.T) {
+		archive := newZip(t, map[string]string{"a.docx": "", "dir/b.XLSX": "", "c.unknown": ""})
+		reports, err := OpenReportArchive(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := map[string]string{}
+		for _, entry := range reports.Entries {
+			types[entry.Name] = entry.ContentType
+		}
+		if types["a.docx"] != "application/vnd.openxmlformats-officedocument.wordprocessingml.document" ||
+			types["dir/b.XLSX"] != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" ||
+			types["c.unknown"] != "application/octet-stream" {
+			t.Error(errors.New("The content types are not correct"), types)
+		}
+	})
+
+	t.Run("Should return an error if the report is not a ZIP archive or batchSplitBy is missing", func(t *testing.T) {
+		if _, err := OpenReportArchive(bytes.NewReader([]byte("%PDF-1.4")), 8); err == nil {
+			t.Error(errors.New("A PDF should have been rejected"))
+		}
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		// ----
+		if _, err := csdk.RenderArchive(context.Background(), templateID, RenderRequest{Data: 1}); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: batchSplitBy is missing and the method should have thrown an error"))
+		}
+		if _, err := csdk.RenderArchive(context.Background(), templateID, RenderRequest{Data: 1, RenderOptions: RenderOptions{BatchReportName: "{d.id}.pdf"}}); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: batchSplitBy is missing and the method should have thrown an error"))
+		}
+		if err := (RenderOptions{BatchReportName: "{d.id}.pdf"}).Validate(); !errors.Is(err, ErrInvalidArgument) {
+			t.Error(errors.New("Test failled: batchReportName requires batchSplitBy"))
+		}
+		if httpmock.GetTotalCallCount() != 0 {
+			t.Fatal(errors.New("HTTPMOCH error - no request should have been sent"))
+		}
+	})
+}
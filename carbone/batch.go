@@ -0,0 +1,255 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// defaultBatchWorkers is the number of reports rendered at the same time by RenderBatch.
+const defaultBatchWorkers = 4
+
+// BatchItem is a report rendered by RenderBatch.
+type BatchItem struct {
+	// Key identifies the item in the results, for instance an invoice number. It is optional.
+	Key string
+	// Template is a template path OR a templateID, like the first argument of Render.
+	Template string
+	// Payload is the optional payload used to generate the templateID of a template path.
+	Payload string
+	// Request is the render body of the report.
+	Request RenderRequest
+}
+
+// BatchOptions configures RenderBatch.
+type BatchOptions struct {
+	// Workers is the number of reports rendered at the same time, 4 if 0.
+	Workers int
+	// StopOnError cancels the items which are not rendered yet when an item fails.
+	StopOnError bool
+	// OnProgress is called after each item, the calls are not concurrent.
+	OnProgress func(progress BatchProgress)
+}
+
+// BatchProgress counts the items of a batch.
+type BatchProgress struct {
+	Total     int
+	Succeeded int
+	Failed    int
+}
+
+// BatchResult is the result of a BatchItem.
+type BatchResult struct {
+	// Index is the position of the item in the batch, Key is the key of the item.
+	Index int
+	Key   string
+	// TemplateID is the template used to render the report.
+	TemplateID string
+	// Report is the content of the report, it is empty if Err is not nil.
+	Report []byte
+	Err    error
+}
+
+// RenderBatch renders many reports concurrently with a pool of opts.Workers goroutines. Like Render, a template path is
+// rendered with its templateID first, the template is uploaded once when Carbone Render does not have it. A result is
+// sent for each item, in the order they are rendered, and the channel is closed once the batch is done. A failed item
+// does not stop the batch unless opts.StopOnError is set, the items which are not rendered yet then fail with
+// context.Canceled.
+func (csdk *CSDK) RenderBatch(ctx context.Context, items []BatchItem, opts BatchOptions) <-chan BatchResult {
+	results := make(chan BatchResult, len(items))
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultBatchWorkers
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+	if csdk.idempotencyKey != "" {
+		// The items are different renders, they can not share the idempotency key of the call
+		csdk = csdk.clone()
+		csdk.idempotencyKey = ""
+	}
+	go func() {
+		defer close(results)
+		ctx, op := csdk.startOperation(ctx, "RenderBatch")
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		b := &batch{csdk: csdk, opts: opts, progress: BatchProgress{Total: len(items)}, results: results, cancel: cancel, templates: map[[2]string]*batchTemplate{}}
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					templateID, report, err := b.render(ctx, items[i])
+					b.done(BatchResult{Index: i, Key: items[i].Key, TemplateID: templateID, Report: report, Err: err})
+				}
+			}()
+		}
+		for i := range items {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		var err error
+		if b.progress.Failed > 0 {
+			err = fmt.Errorf("Carbone SDK RenderBatch error: %d of %d items failed", b.progress.Failed, b.progress.Total)
+		}
+		op.end(err)
+	}()
+	return results
+}
+
+// batch collects the results of RenderBatch.
+type batch struct {
+	csdk    *CSDK
+	opts    BatchOptions
+	results chan<- BatchResult
+	cancel  context.CancelFunc
+
+	mu        sync.Mutex
+	progress  BatchProgress
+	templates map[[2]string]*batchTemplate
+}
+
+// batchTemplate is the templateID of a template path, shared by the items of the batch. mu serializes the upload.
+type batchTemplate struct {
+	mu sync.Mutex
+	id string
+}
+
+// template returns the templateID of a template path and payload, it is generated or read from the template cache
+// once. An expired template is uploaded again.
+func (b *batch) template(ctx context.Context, item BatchItem, info os.FileInfo) (*batchTemplate, string, error) {
+	b.mu.Lock()
+	t, ok := b.templates[[2]string{item.Template, item.Payload}]
+	if !ok {
+		t = &batchTemplate{}
+		b.templates[[2]string{item.Template, item.Payload}] = t
+	}
+	b.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.id == "" {
+		// A failure is returned to the item only, the next item of the template tries again
+		id, expired, err := b.csdk.templateID(item.Template, item.Payload, info)
+		if err != nil {
+			return t, "", err
+		}
+		t.id = id
+		if expired {
+			// The template is about to be deleted by Carbone Render, it is uploaded again before the render
+			if err := b.upload(ctx, t, item); err != nil {
+				return t, "", err
+			}
+		}
+	}
+	return t, t.id, nil
+}
+
+// reupload uploads the template of an item which was not found by Carbone Render with the templateID id. The template
+// is uploaded once: the items which failed with the same templateID render the uploaded template. If the upload
+// fails, the error is returned to the item and the next item which fails with id uploads the template again.
+func (b *batch) reupload(ctx context.Context, t *batchTemplate, item BatchItem, id string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.id == id {
+		if err := b.upload(ctx, t, item); err != nil {
+			return id, err
+		}
+	}
+	return t.id, nil
+}
+
+// upload uploads the template of an item and records its templateID, t.mu must be held.
+func (b *batch) upload(ctx context.Context, t *batchTemplate, item BatchItem) error {
+	resp, err := b.csdk.AddTemplateContext(ctx, item.Template, item.Payload)
+	switch {
+	case err != nil:
+		return err
+	case !resp.Success:
+		return fmt.Errorf("Carbone SDK RenderBatch error: failled to upload the template %s: %s", item.Template, resp.Error)
+	case resp.Data.TemplateID != "":
+		t.id = resp.Data.TemplateID
+	}
+	return nil
+}
+
+// render renders and downloads the report of an item, it returns the templateID used for the render.
+func (b *batch) render(ctx context.Context, item BatchItem) (string, []byte, error) {
+	if item.Template == "" {
+		return "", []byte{}, fmt.Errorf("Carbone SDK RenderBatch error: %w: Template", ErrMissingArgument)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", []byte{}, err
+	}
+	info, err := os.Stat(item.Template)
+	if os.IsNotExist(err) {
+		// The template is a templateID
+		report, err := b.csdk.renderBatchItem(ctx, item.Template, item.Request)
+		return item.Template, report, err
+	}
+	if err != nil {
+		return "", []byte{}, err
+	}
+	if info.IsDir() {
+		return "", []byte{}, errors.New("Carbone SDK RenderBatch error: the template path is a directory")
+	}
+	t, id, err := b.template(ctx, item, info)
+	if err != nil {
+		return id, []byte{}, err
+	}
+	report, err := b.csdk.renderBatchItem(ctx, id, item.Request)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		return id, report, err
+	}
+	// The template does not exist or has been deleted by Carbone Render, upload it and render again
+	if id, err = b.reupload(ctx, t, item, id); err != nil {
+		return id, []byte{}, err
+	}
+	report, err = b.csdk.renderBatchItem(ctx, id, item.Request)
+	return id, report, err
+}
+
+// done sends the result of an item and reports the progress.
+func (b *batch) done(result BatchResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if result.Err != nil {
+		b.progress.Failed++
+		if b.opts.StopOnError {
+			b.cancel()
+		}
+	} else {
+		b.progress.Succeeded++
+	}
+	b.results <- result
+	if b.opts.OnProgress != nil {
+		b.opts.OnProgress(b.progress)
+	}
+}
+
+// renderBatchItem renders and downloads the report of an item.
+func (csdk *CSDK) renderBatchItem(ctx context.Context, templateID string, req RenderRequest) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return []byte{}, err
+	}
+	jsonData, err := marshalRenderRequest(req)
+	if err != nil {
+		return []byte{}, err
+	}
+	cResp, apiErr, err := csdk.renderReport(ctx, templateID, jsonData)
+	if err != nil {
+		return []byte{}, err
+	}
+	if apiErr != nil {
+		return []byte{}, apiErr
+	}
+	if cResp.Data.RenderID == "" {
+		return []byte{}, errors.New("Carbone SDK RenderBatch error: renderID is empty")
+	}
+	return csdk.GetReportContext(ctx, cResp.Data.RenderID)
+}
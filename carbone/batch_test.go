@@ -0,0 +1,247 @@
+package carbone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerBatchResponders mocks Carbone Render: the item with the id failingID fails, the report of the item n is
+// "report n". The templates "known" and "uploaded" and the templates listed in templates exist, an uploaded template
+// has the templateID "uploaded". It returns the maximum number of renders in flight.
+func registerBatchResponders(failingID int, templates ...string) *int32 {
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	known := map[string]bool{"known": true}
+	for _, template := range templates {
+		known[template] = true
+	}
+	httpmock.RegisterResponder("POST", "https://api.carbone.io/template", func(req *http.Request) (*http.Response, error) {
+		if _, _, err := req.FormFile("template"); err != nil {
+			return httpmock.NewStringResponse(http.StatusBadRequest, ""), nil
+		}
+		mu.Lock()
+		known["uploaded"] = true
+		mu.Unlock()
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"templateId": "uploaded"}}`), nil
+	})
+	httpmock.RegisterResponder("POST", `=~^https://api\.carbone\.io/render/`, func(req *http.Request) (*http.Response, error) {
+		template := strings.TrimPrefix(req.URL.Path, "/render/")
+		mu.Lock()
+		exists := known[template]
+		mu.Unlock()
+		if !exists {
+			return httpmock.NewStringResponse(200, `{"success": false, "error": "Error while rendering template Error: 404 Not Found"}`), nil
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		body := struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}{}
+		raw, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(raw, &body)
+		if body.Data.ID == failingID {
+			return httpmock.NewStringResponse(200, `{"success": false, "error": "Error while rendering template"}`), nil
+		}
+		return httpmock.NewStringResponse(200, fmt.Sprintf(`{"success": true, "data": {"renderId": "%s-%d"}}`, template, body.Data.ID)), nil
+	})
+	httpmock.RegisterResponder("GET", `=~^https://api\.carbone\.io/render/`, func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(200, "report "+strings.TrimPrefix(req.URL.Path, "/render/")), nil
+	})
+	return &maxInFlight
+}
+
+// batchUploads returns the number of uploads received by the responders of registerBatchResponders.
+func batchUploads() int {
+	return httpmock.GetCallCountInfo()["POST https://api.carbone.io/template"]
+}
+
+// batchItems returns n items rendering the template
+func batchItems(template string, n int) []BatchItem {
+	items := make([]BatchItem, n)
+	for i := range items {
+		items[i] = BatchItem{Key: fmt.Sprintf("item-%d", i), Template: template, Request: RenderRequest{Data: map[string]int{"id": i}}}
+	}
+	return items
+}
+
+func TestRenderBatch(t *testing.T) {
+	templatePath := "./tests/template.test.html"
+
+	t.Run("Should upload each template once and render the items with a bounded pool", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		maxInFlight := registerBatchResponders(3)
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := append(batchItems(templatePath, 10), BatchItem{Key: "by-id", Template: "known", Request: RenderRequest{Data: map[string]int{"id": 42}}})
+		var last BatchProgress
+		progressCalls := 0
+		results := map[int]BatchResult{}
+		for result := range c.RenderBatch(context.Background(), items, BatchOptions{Workers: 3, OnProgress: func(p BatchProgress) {
+			progressCalls++
+			last = p
+		}}) {
+			results[result.Index] = result
+		}
+		if len(results) != len(items) {
+			t.Fatal(errors.New("A result should have been sent for each item"))
+		}
+		if batchUploads() != 1 {
+			t.Error(errors.New("The template should have been uploaded once"), batchUploads())
+		}
+		if max := atomic.LoadInt32(maxInFlight); max > 3 {
+			t.Error(errors.New("Too many renders in flight"), max)
+		}
+		for i, result := range results {
+			if result.Key != items[i].Key {
+				t.Error(errors.New("The key of the result is not correct"))
+			}
+			if i == 3 {
+				if result.Err == nil || result.Err.Error() != "Error while rendering template" {
+					t.Error(errors.New("The item 3 should have failed"), result.Err)
+				}
+				continue
+			}
+			expected := fmt.Sprintf("report uploaded-%d", i)
+			if i == 10 {
+				expected = "report known-42"
+			}
+			if result.Err != nil || string(result.Report) != expected {
+				t.Error(errors.New("The report is not correct"), result.Err, string(result.Report))
+			}
+		}
+		if progressCalls != len(items) || last != (BatchProgress{Total: 11, Succeeded: 10, Failed: 1}) {
+			t.Error(errors.New("The progress is not reported"), last)
+		}
+	})
+
+	t.Run("Should not upload a template which exists in Carbone Render", func(t *testing.T) {
+		templateID, err := csdk.GenerateTemplateID(templatePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBatchResponders(-1, templateID)
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for result := range c.RenderBatch(context.Background(), batchItems(templatePath, 5), BatchOptions{Workers: 2}) {
+			if result.Err != nil || result.TemplateID != templateID || string(result.Report) != fmt.Sprintf("report %s-%d", templateID, result.Index) {
+				t.Error(errors.New("The report is not correct"), result.Err, string(result.Report))
+			}
+		}
+		if batchUploads() != 0 {
+			t.Error(errors.New("The template should not have been uploaded"), batchUploads())
+		}
+	})
+
+	t.Run("Should cancel the next items when StopOnError is set", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBatchResponders(0)
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		canceled := 0
+		for result := range c.RenderBatch(context.Background(), batchItems("known", 5), BatchOptions{Workers: 1, StopOnError: true}) {
+			if result.Index > 0 && errors.Is(result.Err, context.Canceled) {
+				canceled++
+			}
+		}
+		if canceled != 4 {
+			t.Error(errors.New("The items after the failure should have been canceled"), canceled)
+		}
+	})
+
+	t.Run("Should upload the template again for the next item when an upload failed", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBatchResponders(-1)
+		var uploads int32
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", func(req *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&uploads, 1) == 1 {
+				return httpmock.NewStringResponse(200, `{"success": false, "error": "Service unavailable"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"templateId": "known"}}`), nil
+		})
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for result := range c.RenderBatch(context.Background(), batchItems(templatePath, 3), BatchOptions{Workers: 1}) {
+			if result.Index == 0 {
+				if result.Err == nil || !strings.Contains(result.Err.Error(), "Service unavailable") {
+					t.Error(errors.New("The failed upload should have been returned to the first item"), result.Err)
+				}
+				continue
+			}
+			if result.Err != nil || string(result.Report) != fmt.Sprintf("report known-%d", result.Index) {
+				t.Error(errors.New("The next items should have uploaded the template again"), result.Err, string(result.Report))
+			}
+		}
+		if atomic.LoadInt32(&uploads) != 2 {
+			t.Error(errors.New("The template should have been uploaded twice"), atomic.LoadInt32(&uploads))
+		}
+	})
+
+	t.Run("Should fail the items of a template which can not be uploaded", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBatchResponders(-1)
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := []BatchItem{{Template: "", Request: RenderRequest{Data: 1}}, {Template: "./tests", Request: RenderRequest{Data: 1}}, {Template: "known", Request: RenderRequest{}}}
+		failed := 0
+		for result := range c.RenderBatch(context.Background(), items, BatchOptions{}) {
+			if result.Err != nil {
+				failed++
+			}
+			if result.Index == 0 && !errors.Is(result.Err, ErrMissingArgument) {
+				t.Error(errors.New("The missing template should have been reported"))
+			}
+		}
+		if failed != 3 {
+			t.Error(errors.New("Every item should have failed"), failed)
+		}
+		if _, ok := <-c.RenderBatch(context.Background(), nil, BatchOptions{}); ok {
+			t.Error(errors.New("An empty batch should close the channel"))
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package carbone
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CallOption configures the CSDK of a call, see WithCallOptions.
+type CallOption func(*callOptions) error
+
+// callOptions are the settings of a call, they take precedence over the settings of the CSDK.
+type callOptions struct {
+	// headers are the custom Carbone headers of the call, the keys are canonical
+	headers        map[string]string
+	timeout        *time.Duration
+	apiVersion     string
+	idempotencyKey string
+}
+
+// WithCallOptions returns a copy of the CSDK applying opts on top of its settings, for instance for one call. csdk is
+// not modified: concurrent calls on the same CSDK can use different options. Every request of the copy uses the
+// options, including the uploads of Render and RenderBatch, except the idempotency key sent only by the render
+// requests of Render, RenderInline and RenderAsync. The copy is cheap, see WithToken.
+//
+//	call, err := csdk.WithCallOptions(carbone.CallTimeout(5*time.Minute), carbone.CallTemplateTTL(time.Hour))
+//	report, err := call.RenderContext(ctx, "./invoice.docx", jsonData)
+func (csdk *CSDK) WithCallOptions(opts ...CallOption) (*CSDK, error) {
+	o := callOptions{headers: map[string]string{}}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	c := csdk.clone()
+	if len(o.headers) > 0 {
+		// The headers of the call replace the headers of the CSDK with the same canonical key
+		headers := make(map[string]string, len(c.apiHeaders)+len(o.headers))
+		for k, v := range c.apiHeaders {
+			if _, ok := o.headers[http.CanonicalHeaderKey(k)]; !ok {
+				headers[k] = v
+			}
+		}
+		for k, v := range o.headers {
+			headers[k] = v
+		}
+		c.apiHeaders = headers
+	}
+	if o.timeout != nil {
+		// The client is copied, its transport and its connections are shared
+		client := *c.apiHTTPClient
+		client.Timeout = *o.timeout
+		c.apiHTTPClient = &client
+		c.apiTimeOut = *o.timeout
+	}
+	if o.apiVersion != "" {
+		c.apiVersion = o.apiVersion
+	}
+	if o.idempotencyKey != "" {
+		c.idempotencyKey = o.idempotencyKey
+	}
+	return c, nil
+}
+
+// setIdempotencyKey adds the idempotency key of the call, if any, to the headers of a render request.
+func (csdk *CSDK) setIdempotencyKey(headers map[string]string) {
+	if csdk.idempotencyKey != "" {
+		headers["Idempotency-Key"] = csdk.idempotencyKey
+	}
+}
+
+// CallHeaders sets custom Carbone headers for the call. They take precedence over the headers of SetAPIHeaders,
+// the headers set by the SDK for a request, for instance the webhook of RenderAsync, take precedence over them.
+func CallHeaders(headers map[string]string) CallOption {
+	return func(o *callOptions) error {
+		for k, v := range headers {
+			o.headers[http.CanonicalHeaderKey(k)] = v
+		}
+		return nil
+	}
+}
+
+// CallTimeout sets the time limit of each request of the call, 0 means no timeout. It replaces the timeout of the CSDK.
+func CallTimeout(timeOut time.Duration) CallOption {
+	return func(o *callOptions) error {
+		if timeOut < 0 {
+			return fmt.Errorf("Carbone SDK CallTimeout error: the timeout must be positive, got %v", timeOut)
+		}
+		o.timeout = &timeOut
+		return nil
+	}
+}
+
+// CallWebhookURL sets the "carbone-webhook-url" header of the call: Carbone Render sends the renderId to webhookURL
+// instead of answering it, see https://carbone.io/api-reference.html#api-webhook
+func CallWebhookURL(webhookURL string) CallOption {
+	return func(o *callOptions) error {
+		if err := checkWebhookURL("CallWebhookURL", webhookURL); err != nil {
+			return err
+		}
+		o.headers["Carbone-Webhook-Url"] = webhookURL
+		return nil
+	}
+}
+
+// CallTemplateTTL sets the "carbone-template-delete-after" header of the call, the storage duration of the templates
+// uploaded by the call, see https://carbone.io/api-reference.html#template-storage
+func CallTemplateTTL(ttl time.Duration) CallOption {
+	return func(o *callOptions) error {
+		if ttl < 0 {
+			return fmt.Errorf("Carbone SDK CallTemplateTTL error: the TTL must be positive, got %v", ttl)
+		}
+		o.headers["Carbone-Template-Delete-After"] = strconv.Itoa(int(ttl / time.Second))
+		return nil
+	}
+}
+
+// CallIdempotencyKey sets the "Idempotency-Key" header of the render requests of the call, the retries of a
+// request send the same key. RenderBatch does not send it, the key would be shared by every item.
+func CallIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) error {
+		if key == "" {
+			return errors.New("Carbone SDK CallIdempotencyKey error: the key is empty")
+		}
+		o.idempotencyKey = key
+		return nil
+	}
+}
+
+// CallAPIVersion sets the Carbone Render version requested by the call, the features of the version are used.
+func CallAPIVersion(version int) CallOption {
+	return func(o *callOptions) error {
+		if version <= 0 {
+			return fmt.Errorf("Carbone SDK CallAPIVersion error: the version must be greater than 0, got %d", version)
+		}
+		o.apiVersion = strconv.Itoa(version)
+		return nil
+	}
+}
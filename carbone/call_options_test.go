@@ -0,0 +1,171 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerHeadersResponder mocks Carbone Render answering a templateId and a renderId, the path "/slow" is answered
+// after 200ms. It returns the headers received by path.
+func registerHeadersResponder() func(path string) http.Header {
+	var mu sync.Mutex
+	headers := map[string]http.Header{}
+	httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		headers[req.URL.Path] = req.Header.Clone()
+		mu.Unlock()
+		if req.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"templateId": "tpl", "renderId": "report"}}`), nil
+	})
+	return func(path string) http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+		return headers[path]
+	}
+}
+
+func TestCallOptions(t *testing.T) {
+	// ---- httpmock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	received := registerHeadersResponder()
+	// ----
+	c, err := New(WithToken("token"), WithHeaders(map[string]string{
+		"carbone-webhook-url":           "https://example.com/default",
+		"carbone-template-delete-after": "86400",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData := `{"data": {}}`
+
+	t.Run("Should layer the options of the call on top of the headers of the CSDK", func(t *testing.T) {
+		call, err := c.WithCallOptions(CallWebhookURL("https://example.com/call"), CallHeaders(map[string]string{"x-custom": "custom"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := call.doJSONRequest(context.Background(), "Test", "GET", "https://api.carbone.io/call", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		h := received("/call")
+		if h.Get("carbone-webhook-url") != "https://example.com/call" || h.Get("x-custom") != "custom" || h.Get("carbone-template-delete-after") != "86400" {
+			t.Error(errors.New("The headers of the call are not correct"), h)
+		}
+		if _, _, err := c.doJSONRequest(context.Background(), "Test", "GET", "https://api.carbone.io/default", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if h := received("/default"); h.Get("carbone-webhook-url") != "https://example.com/default" || h.Get("x-custom") != "" {
+			t.Error(errors.New("The headers of the CSDK should not have been modified"), h)
+		}
+	})
+
+	t.Run("Should keep the headers set by the SDK for the request", func(t *testing.T) {
+		call, err := c.WithCallOptions(CallWebhookURL("https://example.com/call"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := call.RenderAsync(context.Background(), "tpl", RenderRequest{Data: map[string]string{}}, "https://example.com/async"); err != nil {
+			t.Fatal(err)
+		}
+		if h := received("/render/tpl"); h.Get("carbone-webhook-url") != "https://example.com/async" {
+			t.Error(errors.New("The webhook of RenderAsync should take precedence"), h)
+		}
+	})
+
+	t.Run("Should send the idempotency key with the render requests only", func(t *testing.T) {
+		call, err := c.WithCallOptions(CallIdempotencyKey("key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := call.RenderContext(context.Background(), "tpl", jsonData); err != nil {
+			t.Fatal(err)
+		}
+		if received("/render/tpl").Get("Idempotency-Key") != "key" || received("/render/report").Get("Idempotency-Key") != "" {
+			t.Error(errors.New("The key should have been sent with the render request only"))
+		}
+		req := RenderRequest{Data: map[string]string{}}
+		for res := range call.RenderBatch(context.Background(), []BatchItem{{Template: "tpl", Request: req}, {Template: "tpl", Request: req}}, BatchOptions{}) {
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+		}
+		if received("/render/tpl").Get("Idempotency-Key") != "" {
+			t.Error(errors.New("The items of a batch should not share the key"))
+		}
+	})
+
+	t.Run("Should request the API version of the call", func(t *testing.T) {
+		call, err := c.WithCallOptions(CallAPIVersion(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := call.doJSONRequest(context.Background(), "Test", "GET", "https://api.carbone.io/version", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if h := received("/version"); h.Get("carbone-version") != "5" {
+			t.Error(errors.New("The version of the call should have been requested"), h)
+		}
+		if call.Features().Version != 5 || c.Features().Version != 4 {
+			t.Error(errors.New("The version of the CSDK should not have been modified"))
+		}
+	})
+
+	t.Run("Should apply the timeout of the call", func(t *testing.T) {
+		call, err := c.WithCallOptions(CallTimeout(20 * time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := call.doJSONRequest(context.Background(), "Test", "GET", "https://api.carbone.io/slow", nil, nil); err == nil {
+			t.Error(errors.New("The request should have timed out"))
+		}
+		if _, _, err := c.doJSONRequest(context.Background(), "Test", "GET", "https://api.carbone.io/slow", nil, nil); err != nil {
+			t.Error(errors.New("The timeout of the CSDK should not have been modified"), err)
+		}
+		if call.apiHTTPClient.Transport != c.apiHTTPClient.Transport {
+			t.Error(errors.New("The transport should be shared"))
+		}
+	})
+
+	t.Run("Should send concurrent calls with their own options", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				tenant := string(rune('a' + i))
+				call, err := c.WithCallOptions(CallHeaders(map[string]string{"x-tenant": tenant}))
+				if err == nil {
+					_, _, err = call.doJSONRequest(context.Background(), "Test", "GET", "https://api.carbone.io/concurrent/"+tenant, nil, nil)
+				}
+				if err == nil && received("/concurrent/"+tenant).Get("x-tenant") != tenant {
+					err = errors.New("The header of the call " + tenant + " is not correct")
+				}
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("Should reject the invalid options", func(t *testing.T) {
+		for _, opt := range []CallOption{CallTimeout(-time.Second), CallWebhookURL("/webhook"), CallTemplateTTL(-time.Second), CallIdempotencyKey(""), CallAPIVersion(0)} {
+			if _, err := c.WithCallOptions(opt); err == nil {
+				t.Error(errors.New("The option should have been rejected"))
+			}
+		}
+	})
+}
@@ -5,18 +5,20 @@ package carbone
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // APIResponseData object created during Carbone Render response.
@@ -24,6 +26,8 @@ type APIResponseData struct {
 	TemplateID            string `json:"templateId,omitempty"`
 	RenderID              string `json:"renderId,omitempty"`
 	TemplateFileExtension string `json:"inputFileExtension,omitempty"`
+	// VersionID is the version of the template returned by an upload with the API version 5
+	VersionID string `json:"versionId,omitempty"`
 }
 
 // APIResponse object created during Carbone Render response.
@@ -35,121 +39,86 @@ type APIResponse struct {
 }
 
 // CSDK (CarboneSDK) to use Carbone render API easily.
+// A CSDK is safe for concurrent use by multiple goroutines, including its Set methods: a request uses the settings
+// read when it is sent. Use WithToken and WithHeaders to get a client per tenant.
 type CSDK struct {
+	// mu guards the settings changed by the Set methods and Use, see settings
+	mu             sync.RWMutex
 	apiVersion     string
 	apiHeaders     map[string]string
 	apiAccessToken string
 	apiURL         string
 	apiTimeOut     time.Duration
 	apiHTTPClient  *http.Client
+	apiUserAgent   string
+	retryPolicy    RetryPolicy
+	logger         Logger
+	telemetry      *telemetry
+	middlewares    []Middleware
+	limiter        *rateLimiter
+	templateCache  TemplateCache
+	directDownload bool
+	// idempotencyKey is sent with the render requests, see CallIdempotencyKey
+	idempotencyKey string
 }
 
-// NewCarboneSDK is a constructor and return a new instance of CSDK
+// NewCarboneSDK is a constructor and return a new instance of CSDK.
+// args[0] is an optional access token and args[1] an optional API URL, see New to pass other options.
+// A warning is printed on the standard output if the access token is missing.
 func NewCarboneSDK(args ...string) (*CSDK, error) {
-	apiURL := os.Getenv("CARBONE_URL")
-	apiAccessToken := os.Getenv("CARBONE_TOKEN")
+	opts := []Option{WithLogger(writerLogger{w: os.Stdout, minLevel: LevelWarn})}
 	if len(args) > 0 && args[0] != "" {
-		apiAccessToken = args[0]
-	}
-	if apiAccessToken == "" {
-		fmt.Println(`Carbone SDK Warning: Cloud API access token and "CARBONE_TOKEN" env variable are missing`)
+		opts = append(opts, WithToken(args[0]))
 	}
 	if len(args) == 2 && args[1] != "" {
-		apiURL = args[1]
-	}
-	if apiURL == "" {
-		apiURL = "https://api.carbone.io"
+		opts = append(opts, WithBaseURL(args[1]))
 	}
-	csdk := &CSDK{
-		apiVersion:     "4",
-		apiAccessToken: apiAccessToken,
-		apiURL:         apiURL,
-		apiTimeOut:     time.Second * 60,
-		apiHTTPClient:  &http.Client{Timeout: time.Second * 60},
-	}
-	return csdk, nil
+	return New(opts...)
 }
 
 // AddTemplate upload your template to Carbone Render. The first parameter is the template file path, the second is an optional payload.
 func (csdk *CSDK) AddTemplate(templateFileName string, args ...string) (APIResponse, error) {
+	return csdk.AddTemplateContext(context.Background(), templateFileName, args...)
+}
+
+// AddTemplateContext is like AddTemplate but the upload is bound to the context ctx.
+func (csdk *CSDK) AddTemplateContext(ctx context.Context, templateFileName string, args ...string) (APIResponse, error) {
 	payload := ""
 	if len(args) > 0 && args[0] != "" {
 		payload = args[0]
 	}
-	cResp := APIResponse{}
-	if templateFileName == "" {
-		return cResp, errors.New("Carbone SDK AddTemplate error: argument is missing: templateFileName")
-	}
-	// Create buffer
-	buf := new(bytes.Buffer)
-	// create a tmpfile and assemble your multipart from there
-	w := multipart.NewWriter(buf)
-	// Create the data object to send
-	// { "payload":"", "template": readstream(file...) }
-	label, err := w.CreateFormField("payload")
-	if err != nil {
-		return cResp, err
-	}
-	// Write payload content (empty for now)
-	label.Write([]byte(payload))
-	// Create the FormData
-	fw, err := w.CreateFormFile("template", templateFileName)
-	if err != nil {
-		return cResp, err
-	}
-	// Open Template
-	fd, err := os.Open(templateFileName)
-	if err != nil {
-		return cResp, err
-	}
-	defer fd.Close()
-	// Write file field from file to upload
-	_, err = io.Copy(fw, fd)
-	if err != nil {
-		return cResp, err
-	}
-	// Important if you do not close the multipart writer you will not have a terminating boundry
-	w.Close()
-	// Create the request
-	headerRequest := map[string]string{
-		"Content-Type": w.FormDataContentType(),
-	}
-	resp, err := csdk.doHTTPRequest("POST", csdk.apiURL+"/template", headerRequest, buf)
-	if err != nil {
-		return cResp, err
-	}
-	// Read the stream
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return cResp, errors.New("Carbone SDK request error: failled to read the body: " + err.Error())
-	}
-	// Close the connection https://stackoverflow.com/questions/33238518/what-could-happen-if-i-dont-close-response-body
-	defer resp.Body.Close()
-	// Parse JSON body and store into the APIResponse Struct
-	err = json.Unmarshal(body, &cResp)
-	if err != nil {
-		return cResp, errors.New("Carbone SDK request error: failled to parse the JSON response from the body: " + err.Error())
-	}
-	return cResp, nil
+	// A response "success": false is returned without error
+	cResp, _, _, err := csdk.addTemplateFile(ctx, "AddTemplate", templateFileName, AddTemplateOptions{Payload: payload})
+	return cResp, err
 }
 
 // GetTemplate returns the original template from the templateId (Unique identifier of the template)
 func (csdk *CSDK) GetTemplate(templateID string) ([]byte, error) {
+	return csdk.GetTemplateContext(context.Background(), templateID)
+}
+
+// GetTemplateContext is like GetTemplate but the request is bound to the context ctx.
+func (csdk *CSDK) GetTemplateContext(ctx context.Context, templateID string) (template []byte, err error) {
+	ctx, op := csdk.startOperation(ctx, "GetTemplate", attrTemplateID.String(templateID))
+	defer func() { op.end(err) }()
 	if templateID == "" {
-		return []byte{}, errors.New("Carbone SDK GetTemplate error: argument is missing: templateID")
+		return []byte{}, fmt.Errorf("Carbone SDK GetTemplate error: %w: templateID", ErrMissingArgument)
 	}
 	// Create the request
-	resp, err := csdk.doHTTPRequest("GET", csdk.apiURL+"/template/"+templateID, nil, nil)
+	resp, err := csdk.doHTTPRequest(ctx, "GET", csdk.dialect().templateURL(csdk.apiURL, templateID), nil, nil)
 	if err != nil {
 		return []byte{}, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return []byte{}, newAPIError(resp)
+	}
+	// Close the connection
+	defer resp.Body.Close()
 	// Read the response data and return a []byte. The http package automatically decodes chunking when reading response body.
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return []byte{}, errors.New("Carbone SDK GetTemplate request error: failled to read the body: " + err.Error())
 	}
-	// Close the connection
-	defer resp.Body.Close()
 	if len(body) == 0 {
 		return body, errors.New("Carbone SDK GetTemplate request error: The response body is empty")
 	}
@@ -158,78 +127,103 @@ func (csdk *CSDK) GetTemplate(templateID string) ([]byte, error) {
 
 // DeleteTemplate Delete an uploaded template from a templateID.
 func (csdk *CSDK) DeleteTemplate(templateID string) (APIResponse, error) {
-	cResp := APIResponse{}
+	return csdk.DeleteTemplateContext(context.Background(), templateID)
+}
+
+// DeleteTemplateContext is like DeleteTemplate but the request is bound to the context ctx.
+func (csdk *CSDK) DeleteTemplateContext(ctx context.Context, templateID string) (APIResponse, error) {
+	ctx, op := csdk.startOperation(ctx, "DeleteTemplate", attrTemplateID.String(templateID))
 	if templateID == "" {
-		return cResp, errors.New("Carbone SDK DeleteTemplate error: argument is missing: templateID")
+		err := fmt.Errorf("Carbone SDK DeleteTemplate error: %w: templateID", ErrMissingArgument)
+		op.end(err)
+		return APIResponse{}, err
 	}
-	// HTTP Request
-	resp, err := csdk.doHTTPRequest("DELETE", csdk.apiURL+"/template/"+templateID, nil, nil)
-	if err != nil {
-		return cResp, err
-	}
-	// Read body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return cResp, errors.New("Carbone SDK DeleteTemplate request error: failled to read the body: " + err.Error())
-	}
-	// Close the connection
-	defer resp.Body.Close()
-	// Parse JSON body and store into the APIResponse Struct
-	err = json.Unmarshal(body, &cResp)
-	if err != nil {
-		return cResp, errors.New("Carbone SDK DeleteTemplate request error: failled to parse the JSON response from the body: " + err.Error())
-	}
-	return cResp, nil
+	cResp, apiErr, err := csdk.doJSONRequest(ctx, "DeleteTemplate", "DELETE", csdk.dialect().templateURL(csdk.apiURL, templateID), nil, nil)
+	op.end(resultError(apiErr, err))
+	return cResp, err
 }
 
 // RenderReport a report from a templateID and a json data
 func (csdk *CSDK) RenderReport(templateID string, jsonData string) (APIResponse, error) {
-	cResp := APIResponse{}
+	return csdk.RenderReportContext(context.Background(), templateID, jsonData)
+}
+
+// RenderReportContext is like RenderReport but the request is bound to the context ctx.
+func (csdk *CSDK) RenderReportContext(ctx context.Context, templateID string, jsonData string) (APIResponse, error) {
+	cResp, _, err := csdk.renderReport(ctx, templateID, jsonData)
+	return cResp, err
+}
+
+// renderReport renders a report and returns the failure as an APIError if the API answers success false.
+func (csdk *CSDK) renderReport(ctx context.Context, templateID string, jsonData string) (APIResponse, *APIError, error) {
+	cResp, apiErr, _, err := csdk.sendRender(ctx, templateID, jsonData, false)
+	return cResp, apiErr, err
+}
+
+// sendRender sends the render request of a report. If download is set, the report is requested in the response:
+// it is returned if the API sent it, otherwise the renderId of the response must be downloaded.
+func (csdk *CSDK) sendRender(ctx context.Context, templateID string, jsonData string, download bool) (cResp APIResponse, apiErr *APIError, report *Report, err error) {
+	ctx, op := csdk.startOperation(ctx, "RenderReport", attrTemplateID.String(templateID))
+	defer func() {
+		op.setAttributes(attrRenderID.String(cResp.Data.RenderID))
+		op.end(resultError(apiErr, err))
+	}()
 	if templateID == "" {
-		return cResp, errors.New("Carbone SDK RenderReport error: argument is missing: templateID")
+		return APIResponse{}, nil, nil, fmt.Errorf("Carbone SDK RenderReport error: %w: templateID", ErrMissingArgument)
 	}
 	if jsonData == "" {
-		return cResp, errors.New("Carbone SDK RenderReport error: argument is missing: jsonData")
+		return APIResponse{}, nil, nil, fmt.Errorf("Carbone SDK RenderReport error: %w: jsonData", ErrMissingArgument)
 	}
+	return csdk.postRender(ctx, "RenderReport", csdk.dialect().renderURL(csdk.apiURL, templateID, download), jsonData, download)
+}
+
+// postRender sends a render request to url, op is the name of the method used in error messages.
+func (csdk *CSDK) postRender(ctx context.Context, op string, url string, jsonData string, download bool) (APIResponse, *APIError, *Report, error) {
 	headerRequest := map[string]string{
 		"Content-Type": "application/json",
 	}
-	resp, err := csdk.doHTTPRequest("POST", csdk.apiURL+"/render/"+templateID, headerRequest, bytes.NewBuffer([]byte(jsonData)))
-	if err != nil {
-		return cResp, err
-	}
-	body, err := ioutil.ReadAll(resp.Body)
+	csdk.setIdempotencyKey(headerRequest)
+	resp, err := csdk.doHTTPRequest(ctx, "POST", url, headerRequest, bytes.NewBuffer([]byte(jsonData)))
 	if err != nil {
-		return cResp, errors.New("Carbone SDK RenderReport request error: failled to read the body: " + err.Error())
+		return APIResponse{}, nil, nil, err
 	}
-	// Close the connection
-	defer resp.Body.Close()
-	err = json.Unmarshal(body, &cResp)
-	if err != nil {
-		return cResp, errors.New("Carbone SDK RenderReport request error: failled to parse the JSON response from the body: " + err.Error())
+	if download && resp.StatusCode == http.StatusOK && !isJSONResponse(resp) && !isJSONBody(resp) {
+		// The API sent the report instead of its renderId
+		report, err := csdk.newReport(ctx, op, resp)
+		return APIResponse{Success: true}, nil, report, err
 	}
-	return cResp, nil
+	// The API does not support the direct download, or the render failed
+	cResp, apiErr, err := csdk.decodeJSONResponse(op, resp)
+	return cResp, apiErr, nil, err
 }
 
 // GetReport Request Carbone Render and return a generated report
 func (csdk *CSDK) GetReport(renderID string) ([]byte, error) {
-	if renderID == "" {
-		return []byte{}, errors.New("Carbone SDK GetReport error: argument is missing: renderID")
-	}
-	// http request
-	resp, err := csdk.doHTTPRequest("GET", csdk.apiURL+"/render/"+renderID, nil, nil)
+	return csdk.GetReportContext(context.Background(), renderID)
+}
+
+// GetReportContext is like GetReport but the download is bound to the context ctx.
+func (csdk *CSDK) GetReportContext(ctx context.Context, renderID string) (body []byte, err error) {
+	ctx, op := csdk.startOperation(ctx, "GetReport", attrRenderID.String(renderID))
+	defer func() { op.end(err) }()
+	report, err := csdk.openReport(ctx, "GetReport", renderID)
 	if err != nil {
 		return []byte{}, err
 	}
+	return readReport("GetReport", report)
+}
+
+// readReport reads and closes a report, op is the name of the method used in error messages.
+func readReport(op string, report *Report) ([]byte, error) {
+	// Close the connection
+	defer report.Close()
 	// Read the response data and return a []byte. The http package automatically decodes chunking when reading response body.
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := ioutil.ReadAll(report)
 	if err != nil {
-		return []byte{}, errors.New("Carbone SDK GetReport request error: failled to read the body: " + err.Error())
+		return []byte{}, fmt.Errorf("Carbone SDK %s request error: failled to read the body: %w", op, err)
 	}
-	// Close the connection
-	defer resp.Body.Close()
 	if len(body) == 0 {
-		return []byte{}, errors.New("Carbone SDK GetReport request error: The response body is empty: Render again and generate a new renderId")
+		return []byte{}, fmt.Errorf("Carbone SDK %s request error: The response body is empty: Render again and generate a new renderId: %w", op, ErrReportExpired)
 	}
 	return body, nil
 }
@@ -240,55 +234,130 @@ func (csdk *CSDK) GetReport(renderID string) ([]byte, error) {
 // args {...string}: You can pass an optinal payload used during the template upload (AddTemplate) to create a different templateID.
 // It returns a []byte of the file.
 func (csdk *CSDK) Render(pathOrTemplateID string, jsonData string, args ...string) ([]byte, error) {
+	return csdk.RenderContext(context.Background(), pathOrTemplateID, jsonData, args...)
+}
+
+// RenderContext is like Render but every request sent to Carbone Render is bound to the context ctx.
+// Cancelling ctx aborts the render, the template upload or the report download in progress.
+func (csdk *CSDK) RenderContext(ctx context.Context, pathOrTemplateID string, jsonData string, args ...string) (report []byte, err error) {
+	ctx, op := csdk.startOperation(ctx, "Render")
+	defer func() { op.end(err) }()
+	res := RenderResult{}
+	direct, err := csdk.render(ctx, &res, true, pathOrTemplateID, jsonData, args...)
+	if err != nil {
+		return []byte{}, err
+	}
+	if direct != nil {
+		return readReport("Render", direct)
+	}
+	// Return the report
+	return csdk.GetReportContext(ctx, res.RenderID)
+}
+
+// renderID renders a report from a templateID OR a template path, like Render, and returns the renderId of the report.
+func (csdk *CSDK) renderID(ctx context.Context, pathOrTemplateID string, jsonData string, args ...string) (string, error) {
+	res := RenderResult{}
+	_, err := csdk.render(ctx, &res, false, pathOrTemplateID, jsonData, args...)
+	return res.RenderID, err
+}
+
+// render renders a report from a templateID OR a template path, like Render. It fills res with the renderId,
+// the templateID and the timings of the requests. If download is set and the direct download is enabled,
+// the report is returned when the API sends it in the render response, otherwise it must be downloaded.
+func (csdk *CSDK) render(ctx context.Context, res *RenderResult, download bool, pathOrTemplateID string, jsonData string, args ...string) (*Report, error) {
 	var cresp APIResponse
+	var apiErr *APIError
+	var report *Report
 	var er error
+	// The report is requested only if the version supports it, see renderURL
+	download = download && csdk.settings().directDownload && csdk.dialect().features.DirectDownload
 	payload := ""
 	if len(args) > 0 && args[0] != "" {
 		payload = args[0]
 	}
+	renderReport := func(templateID string) {
+		start := time.Now()
+		res.TemplateID = templateID
+		cresp, apiErr, report, er = csdk.sendRender(ctx, templateID, jsonData, download)
+		res.Timings.Render += time.Since(start)
+	}
+	addTemplate := func() (APIResponse, error) {
+		start := time.Now()
+		defer func() { res.Timings.Upload += time.Since(start) }()
+		return csdk.AddTemplateContext(ctx, pathOrTemplateID, payload)
+	}
 	info, err := os.Stat(pathOrTemplateID)
 	if os.IsNotExist(err) {
 		// The first argument `pathOrTemplateID` is a templateID
-		cresp, er = csdk.RenderReport(pathOrTemplateID, jsonData)
+		renderReport(pathOrTemplateID)
 		if er != nil {
-			return []byte{}, er
+			return nil, er
 		}
 	} else if info.IsDir() {
-		return []byte{}, errors.New("Carbone SDK Render error: the path passed as argument is a directory")
+		return nil, errors.New("Carbone SDK Render error: the path passed as argument is a directory")
 	} else {
 		// The first argument `pathOrTemplateID` is maybe a file
-		templateID, e := csdk.GenerateTemplateID(pathOrTemplateID, payload)
+		start := time.Now()
+		_, op := csdk.startOperation(ctx, "GenerateTemplateID")
+		templateID, expired, e := csdk.templateID(pathOrTemplateID, payload, info)
+		op.setAttributes(attrTemplateID.String(templateID))
+		op.end(e)
+		res.Timings.Hash = time.Since(start)
 		if e != nil {
-			return []byte{}, errors.New("Carbone SDK Render error: failled to generate the templateID hash:" + e.Error())
+			return nil, errors.New("Carbone SDK Render error: failled to generate the templateID hash:" + e.Error())
 		}
-		cresp, er = csdk.RenderReport(templateID, jsonData)
-		if er != nil {
-			return []byte{}, er
-		} else if !cresp.Success {
-			// if RenderReport return one of the following error, it means the template does not exist
-			// - Error while rendering template Error: ENOENT:File not found
-			// - Error while rendering template Error: 404 Not Found
-			// Then call add template and render again
-			cres, e := csdk.AddTemplate(pathOrTemplateID, payload)
+		if expired {
+			// The template is about to be deleted by Carbone Render, it is uploaded again before the render
+			cres, e := addTemplate()
 			if e != nil {
-				return []byte{}, errors.New("Carbone SDK Render error:" + e.Error())
+				return nil, &RenderError{TemplateID: templateID, Err: fmt.Errorf("Carbone SDK Render error:%w", e)}
+			}
+			if !cres.Success || cres.Data.TemplateID == "" {
+				return nil, &RenderError{TemplateID: templateID, Err: fmt.Errorf("Carbone SDK Render error: failled to upload the template: %s", cres.Error)}
+			}
+			templateID = cres.Data.TemplateID
+			res.Reuploaded = true
+		}
+		renderReport(templateID)
+		if er != nil && !errors.Is(er, ErrTemplateNotFound) {
+			return nil, &RenderError{TemplateID: templateID, Err: er}
+		} else if er != nil || !cresp.Success {
+			if er == nil && !errors.Is(apiErr, ErrTemplateNotFound) {
+				// The data, the options or the account are rejected: uploading the template again would not help
+				return nil, &RenderError{TemplateID: templateID, Err: apiErr}
+			}
+			// The template does not exist or has been deleted by Carbone Render, upload it and render again
+			cres, e := addTemplate()
+			if e != nil {
+				return nil, &RenderError{TemplateID: templateID, TemplateMissing: true, Err: fmt.Errorf("Carbone SDK Render error:%w", e)}
+			}
+			if cres.Data.TemplateID == "" {
+				return nil, &RenderError{TemplateID: templateID, TemplateMissing: true, Err: fmt.Errorf("Carbone SDK Render error: failled to upload the template: %s", cres.Error)}
 			}
-			cresp, er = csdk.RenderReport(cres.Data.TemplateID, jsonData)
+			res.Reuploaded = true
+			renderReport(cres.Data.TemplateID)
 			if er != nil {
-				return []byte{}, errors.New("Carbone SDK Render error:" + er.Error())
+				return nil, &RenderError{TemplateID: cres.Data.TemplateID, TemplateMissing: true, Reuploaded: true, Err: fmt.Errorf("Carbone SDK Render error:%w", er)}
+			} else if !cresp.Success {
+				return nil, &RenderError{TemplateID: cres.Data.TemplateID, TemplateMissing: true, Reuploaded: true, Err: apiErr}
 			}
 		}
 	}
+	if report != nil {
+		// The report has been downloaded with the render
+		return report, nil
+	}
 	if !cresp.Success {
 		// If an error is returned, it means something went wrong.
-		// if the error is "Error while rendering template Error: 404 Not Found" or "ENOENT:File not found" it means TemplateID does not exist
-		return []byte{}, errors.New(cresp.Error)
+		// if the error is "Error while rendering template Error: 404 Not Found" or "ENOENT:File not found" it means TemplateID does not exist,
+		// errors.Is(err, ErrTemplateNotFound) reports it.
+		return nil, apiErr
 	}
 	if len(cresp.Data.RenderID) <= 0 {
-		return []byte{}, errors.New("Carbone SDK Render error: renderID is empty")
+		return nil, errors.New("Carbone SDK Render error: renderID is empty")
 	}
-	// Return the report
-	return csdk.GetReport(cresp.Data.RenderID)
+	res.RenderID = cresp.Data.RenderID
+	return nil, nil
 }
 
 // GenerateTemplateID Generate the templateID from a template
@@ -320,56 +389,194 @@ func (csdk *CSDK) GenerateTemplateID(filepath string, args ...string) (string, e
 
 // SetAccessToken set the Carbone Render access token
 func (csdk *CSDK) SetAccessToken(newToken string) {
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
 	csdk.apiAccessToken = newToken
 }
 
 // SetAPIVersion set the Carbone Render version
 func (csdk *CSDK) SetAPIVersion(version int) {
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
 	csdk.apiVersion = strconv.Itoa(version)
 }
 
 // GetAPIVersion get the Carbone Render version
 func (csdk *CSDK) GetAPIVersion() (int, error) {
-	return strconv.Atoi(csdk.apiVersion)
+	return strconv.Atoi(csdk.settings().apiVersion)
 }
 
 // SetAPIHeaders get the Carbone Render version
 func (csdk *CSDK) SetAPIHeaders(headers map[string]string) {
-	csdk.apiHeaders = headers
+	// The map is copied, the caller can modify it while requests are sent
+	copied := make(map[string]string, len(headers))
+	for k, v := range headers {
+		copied[k] = v
+	}
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
+	csdk.apiHeaders = copied
+}
+
+// SetRetryPolicy set how failed requests are sent again, see DefaultRetryPolicy. The zero value disables retries.
+// An invalid policy is rejected and the previous policy is kept.
+func (csdk *CSDK) SetRetryPolicy(policy RetryPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
+	csdk.retryPolicy = policy
+	return nil
+}
+
+// settings are the fields of a CSDK changed by the Set methods and Use. The maps and the slices are never
+// modified once they are set, the settings can be read without holding the lock.
+type settings struct {
+	apiVersion     string
+	apiHeaders     map[string]string
+	apiAccessToken string
+	retryPolicy    RetryPolicy
+	middlewares    []Middleware
+	limiter        *rateLimiter
+	templateCache  TemplateCache
+	directDownload bool
+}
+
+// settings returns the current settings of the CSDK.
+func (csdk *CSDK) settings() settings {
+	csdk.mu.RLock()
+	defer csdk.mu.RUnlock()
+	return settings{
+		apiVersion:     csdk.apiVersion,
+		apiHeaders:     csdk.apiHeaders,
+		apiAccessToken: csdk.apiAccessToken,
+		retryPolicy:    csdk.retryPolicy,
+		middlewares:    csdk.middlewares,
+		limiter:        csdk.limiter,
+		templateCache:  csdk.templateCache,
+		directDownload: csdk.directDownload,
+	}
 }
 
 // ------------------ private function
-func (csdk *CSDK) doHTTPRequest(method string, url string, headers map[string]string,
+func (csdk *CSDK) doHTTPRequest(ctx context.Context, method string, url string, headers map[string]string,
 	body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
 		return nil, errors.New("Carbone SDK request: failled to create a new request: " + err.Error())
 	}
+	if sb, ok := body.(*streamBody); ok {
+		// The body is written on the fly, it is generated again if the request is retried
+		req.GetBody = sb.getBody
+		if sb.size >= 0 {
+			req.ContentLength = sb.size
+		}
+	}
 
+	callHeaders := http.Header{}
 	for k, v := range headers {
 		req.Header.Set(k, v)
+		callHeaders.Set(k, v)
 	}
 
-	// User Api Token
-	req.Header.Set("Authorization", "Bearer "+csdk.apiAccessToken)
-	req.Header.Set("carbone-version", csdk.apiVersion)
+	// The "Authorization" and "carbone-version" headers are set by the first middleware, see Use
+	if csdk.apiUserAgent != "" {
+		req.Header.Set("User-Agent", csdk.apiUserAgent)
+	}
 
+	current := csdk.settings()
 	/*
 	* Set custom Carbone headers
 	* - carbone-template-delete-after: https://carbone.io/api-reference.html#template-storage
 	* - carbone-webhook-url: https://carbone.io/api-reference.html#api-webhook
 	 */
-	for k, v := range csdk.apiHeaders {
+	for k, v := range current.apiHeaders {
+		if callHeaders.Get(k) != "" {
+			// The headers of the call take precedence, for instance the webhook of RenderAsync
+			continue
+		}
 		req.Header.Set(k, v)
 	}
 
-	// Send request
-	resp, err := csdk.apiHTTPClient.Do(req)
+	// Propagate the trace context of the operation, W3C "traceparent" by default
+	csdk.telemetry.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	// Send request, it is sent again according to the retry policy
+	start := time.Now()
+	logHeader := req.Header.Clone()
+	csdk.setCarboneHeaders(logHeader)
+	keyvals := append(requestKeyvals(req), "headers", redactHeader(logHeader, current.apiAccessToken))
+	stats := &requestStats{}
+	resp, err := csdk.doWithRetry(ctx, req, stats)
 	if err != nil {
-		return nil, fmt.Errorf("Carbone SDK request error: %v", err.Error())
-	}
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != 404 {
-		return resp, fmt.Errorf("Carbone SDK request error status code %d", resp.StatusCode)
+		keyvals = append(keyvals, "attempts", stats.attempts, "duration", time.Since(start), "error", err)
+		csdk.logger.Log(ctx, LevelDebug, "Carbone request failed", keyvals...)
+		return nil, fmt.Errorf("Carbone SDK request error: %w", err)
+	}
+	if resp.Request == nil {
+		// Some transports do not set it, APIError reads the method and the path from it
+		resp.Request = req
+	}
+	// The request is logged when the body is closed
+	resp.Body = &loggedBody{
+		countingReader: countingReader{ReadCloser: resp.Body},
+		ctx:            ctx,
+		logger:         csdk.logger,
+		start:          start,
+		keyvals:        append(keyvals, "status", resp.StatusCode, "attempts", stats.attempts, "bytesSent", stats.bytesSent()),
+	}
+	// A 404 is answered with a JSON body describing the error, it is parsed by the caller
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return nil, newAPIError(resp)
 	}
 	return resp, nil
 }
+
+// doJSONRequest sends a request to an endpoint answering an APIResponse.
+// If the API answers "success": false, the APIResponse is returned along an APIError describing the failure.
+func (csdk *CSDK) doJSONRequest(ctx context.Context, op string, method string, url string, headers map[string]string,
+	body io.Reader) (APIResponse, *APIError, error) {
+	resp, err := csdk.doHTTPRequest(ctx, method, url, headers, body)
+	if err != nil {
+		return APIResponse{}, nil, err
+	}
+	return csdk.decodeJSONResponse(op, resp)
+}
+
+// decodeJSONResponse reads and closes the JSON response of a request.
+func (csdk *CSDK) decodeJSONResponse(op string, resp *http.Response) (APIResponse, *APIError, error) {
+	cResp, _, apiErr, err := csdk.readJSONResponse(op, resp)
+	return cResp, apiErr, err
+}
+
+// readJSONResponse is like decodeJSONResponse and returns the body of the response too.
+func (csdk *CSDK) readJSONResponse(op string, resp *http.Response) (APIResponse, []byte, *APIError, error) {
+	cResp := APIResponse{}
+	// Close the connection https://stackoverflow.com/questions/33238518/what-could-happen-if-i-dont-close-response-body
+	defer resp.Body.Close()
+	// Read the stream
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return cResp, respBody, nil, fmt.Errorf("Carbone SDK %s request error: failled to read the body: %w", op, err)
+	}
+	// Parse JSON body and store into the APIResponse Struct
+	err = csdk.dialect().decodeResponse(respBody, &cResp)
+	if err != nil && resp.StatusCode >= http.StatusBadRequest {
+		// The API rejected the request without a JSON body, for instance a proxy answering 404 Not Found
+		return cResp, respBody, newAPIErrorFromBody(resp, cResp, respBody), nil
+	}
+	if err != nil {
+		return cResp, respBody, nil, fmt.Errorf("Carbone SDK %s request error: failled to parse the JSON response from the body: %w", op, err)
+	}
+	if lb, ok := resp.Body.(*loggedBody); ok {
+		lb.addIDs(cResp.Data)
+	}
+	if !cResp.Success {
+		return cResp, respBody, newAPIErrorFromBody(resp, cResp, respBody), nil
+	}
+	return cResp, respBody, nil, nil
+}
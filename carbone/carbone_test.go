@@ -1,13 +1,16 @@
 package carbone
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 )
@@ -746,3 +749,88 @@ func TestRender(t *testing.T) {
 		csdk.SetAPIVersion(2)
 	})
 }
+
+func TestContext(t *testing.T) {
+	// newBlockingServer returns a server which signals when a request is received and hangs until the client goes away
+	newBlockingServer := func() (*httptest.Server, chan string) {
+		received := make(chan string, 4)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			received <- req.Method + " " + req.URL.Path
+			// The client disconnection is only detected once the body has been consumed
+			ioutil.ReadAll(req.Body)
+			select {
+			case <-req.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}))
+		return srv, received
+	}
+
+	t.Run("Should abort an in-flight template upload when the context is cancelled", func(t *testing.T) {
+		srv, received := newBlockingServer()
+		defer srv.Close()
+		csdkCtx, err := NewCarboneSDK("token", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-received
+			cancel()
+		}()
+		start := time.Now()
+		resp, err := csdkCtx.AddTemplateContext(ctx, "./tests/template.test.odt")
+		if err == nil || resp.Success == true {
+			t.Fatal(errors.New("Test failled: the upload should have been aborted"))
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal(errors.New("The error should wrap context.Canceled: " + err.Error()))
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatal(errors.New("The upload has not been aborted immediately"))
+		}
+	})
+
+	t.Run("Should abort an in-flight render when the deadline expires", func(t *testing.T) {
+		srv, received := newBlockingServer()
+		defer srv.Close()
+		csdkCtx, err := NewCarboneSDK("token", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+		report, err := csdkCtx.RenderContext(ctx, "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868", jsonData)
+		if err == nil || len(report) > 0 {
+			t.Fatal(errors.New("Test failled: the render should have been aborted"))
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal(errors.New("The error should wrap context.DeadlineExceeded: " + err.Error()))
+		}
+		if r := <-received; r != "POST /render/f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868" {
+			t.Fatal(errors.New("Unexpected request: " + r))
+		}
+	})
+
+	t.Run("Should not send any request with an already cancelled context", func(t *testing.T) {
+		srv, received := newBlockingServer()
+		defer srv.Close()
+		csdkCtx, err := NewCarboneSDK("token", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report, err := csdkCtx.GetReportContext(ctx, "r3209jf903j2f90j2309fj3209fj")
+		if err == nil || len(report) > 0 {
+			t.Fatal(errors.New("Test failled: the download should have been aborted"))
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal(errors.New("The error should wrap context.Canceled: " + err.Error()))
+		}
+		if len(received) != 0 {
+			t.Fatal(errors.New("No request should have reached the server"))
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package carbone
+
+// WithToken returns a copy of the CSDK sending the requests with the access token token, for instance a client per
+// tenant. The copy is cheap: it shares the HTTP client and its connections, the logger, the telemetry, the rate limit
+// and the template cache of csdk. The Set methods of the copy do not modify csdk, and the other way around.
+func (csdk *CSDK) WithToken(token string) *CSDK {
+	c := csdk.clone()
+	c.apiAccessToken = token
+	return c
+}
+
+// WithHeaders returns a copy of the CSDK sending the custom Carbone headers headers instead of the headers set by
+// SetAPIHeaders, see WithToken.
+func (csdk *CSDK) WithHeaders(headers map[string]string) *CSDK {
+	c := csdk.clone()
+	c.SetAPIHeaders(headers)
+	return c
+}
+
+// clone returns a copy of the CSDK with the current settings.
+func (csdk *CSDK) clone() *CSDK {
+	current := csdk.settings()
+	return &CSDK{
+		apiVersion:     current.apiVersion,
+		apiHeaders:     current.apiHeaders,
+		apiAccessToken: current.apiAccessToken,
+		apiURL:         csdk.apiURL,
+		apiTimeOut:     csdk.apiTimeOut,
+		apiHTTPClient:  csdk.apiHTTPClient,
+		apiUserAgent:   csdk.apiUserAgent,
+		retryPolicy:    current.retryPolicy,
+		logger:         csdk.logger,
+		telemetry:      csdk.telemetry,
+		middlewares:    current.middlewares,
+		limiter:        current.limiter,
+		templateCache:  current.templateCache,
+		directDownload: current.directDownload,
+		idempotencyKey: csdk.idempotencyKey,
+	}
+}
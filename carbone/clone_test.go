@@ -0,0 +1,143 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerTokenResponder mocks Carbone Render answering a renderId and a report. It returns the values of the
+// "Authorization" and "x-tenant" headers received, separated by a space.
+func registerTokenResponder() func() []string {
+	var mu sync.Mutex
+	var received []string
+	httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		received = append(received, req.Header.Get("Authorization")+" "+req.Header.Get("x-tenant"))
+		mu.Unlock()
+		if req.Method == "GET" {
+			return httpmock.NewStringResponse(200, "report"), nil
+		}
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "report"}}`), nil
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, received...)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	// ---- httpmock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	received := registerTokenResponder()
+	// ----
+	c, err := New(WithToken("token-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData := `{"data": {}}`
+
+	t.Run("Should change the settings while requests are sent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				report, err := c.RenderContext(context.Background(), "template", jsonData)
+				if err == nil && string(report) != "report" {
+					err = errors.New("The report is not correct")
+				}
+				errs <- err
+			}()
+		}
+		for i := 0; i < 20; i++ {
+			c.SetAccessToken("token-" + string(rune('a'+i)))
+			c.SetAPIVersion(4)
+			c.SetAPIHeaders(map[string]string{"x-tenant": "tenant"})
+			if err := c.SetRetryPolicy(RetryPolicy{}); err != nil {
+				t.Fatal(err)
+			}
+			c.SetDirectDownload(false)
+			c.SetTemplateCache(nil)
+			if err := c.SetRateLimit(RateLimit{MaxInFlight: 100}); err != nil {
+				t.Fatal(err)
+			}
+			c.Use(func(next http.RoundTripper) http.RoundTripper { return next })
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		for _, r := range received() {
+			if !strings.HasPrefix(r, "Bearer token-") {
+				t.Error(errors.New("The token of a request is not correct"), r)
+			}
+		}
+	})
+
+	t.Run("Should not share the headers map of the caller", func(t *testing.T) {
+		headers := map[string]string{"x-tenant": "before"}
+		c.SetAPIHeaders(headers)
+		headers["x-tenant"] = "after"
+		if c.settings().apiHeaders["x-tenant"] != "before" {
+			t.Error(errors.New("The headers should have been copied"))
+		}
+	})
+}
+
+func TestClone(t *testing.T) {
+	// ---- httpmock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	received := registerTokenResponder()
+	// ----
+	c, err := New(WithToken("parent"), WithHeaders(map[string]string{"x-tenant": "parent"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should send the requests of a clone with its token and its headers", func(t *testing.T) {
+		tenant := c.WithToken("tenant-token").WithHeaders(map[string]string{"x-tenant": "tenant"})
+		if _, err := tenant.RenderReportContext(context.Background(), "template", `{"data": {}}`); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.RenderReportContext(context.Background(), "template", `{"data": {}}`); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(received(), ", "); got != "Bearer tenant-token tenant, Bearer parent parent" {
+			t.Error(errors.New("The clone should not have modified the parent"), got)
+		}
+	})
+
+	t.Run("Should share the HTTP client of the parent", func(t *testing.T) {
+		tenant := c.WithToken("tenant-token")
+		if tenant.apiHTTPClient != c.apiHTTPClient || tenant.telemetry != c.telemetry || tenant.apiURL != c.apiURL {
+			t.Error(errors.New("The clone should share the transport of the parent"))
+		}
+	})
+
+	t.Run("Should not change the parent when a clone is configured", func(t *testing.T) {
+		tenant := c.WithToken("tenant-token")
+		tenant.SetAPIVersion(5)
+		tenant.Use(func(next http.RoundTripper) http.RoundTripper { return next })
+		tenant.SetDirectDownload(true)
+		if c.Features().Version != 4 || len(c.settings().middlewares) != 0 || c.settings().directDownload {
+			t.Error(errors.New("The settings of the parent should not have been modified"))
+		}
+		c.SetAccessToken("rotated")
+		if tenant.settings().apiAccessToken != "tenant-token" {
+			t.Error(errors.New("The settings of the clone should not have been modified"))
+		}
+	})
+}
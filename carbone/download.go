@@ -0,0 +1,70 @@
+package carbone
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxSniffedJSONSize is the size of the largest body without a JSON media type which can be the JSON of the API.
+const maxSniffedJSONSize = 64 << 10
+
+// WithDirectDownload enables the direct download of the reports, see SetDirectDownload.
+func WithDirectDownload(enabled bool) Option {
+	return func(o *options) error {
+		o.directDownload = enabled
+		return nil
+	}
+}
+
+// SetDirectDownload enables the direct download of the reports rendered by Render, RenderStream, RenderTo and
+// RenderWithResult: the report is requested in the response of the render, saving the download request.
+// It requires the API version 5, see Features. If the API answers with a renderId anyway, the report is downloaded
+// with a second request. It is disabled by default.
+func (csdk *CSDK) SetDirectDownload(enabled bool) {
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
+	csdk.directDownload = enabled
+}
+
+// isJSONResponse reports whether the body of a response is the JSON of the API, a report is sent with its own
+// media type or as an attachment.
+func isJSONResponse(resp *http.Response) bool {
+	if resp.Header.Get("Content-Disposition") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || mediaType == "text/json")
+}
+
+// isJSONBody reports whether the body of a response without media type or with a text media type is the JSON of
+// the API, for instance sent by a proxy or a mock which does not set the Content-Type. The beginning of the body is
+// buffered to be decoded, the body of resp is read from the start afterwards.
+func isJSONBody(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.Header.Get("Content-Disposition") != "" || (mediaType != "" && !strings.HasPrefix(mediaType, "text/")) {
+		return false
+	}
+	// The buffer is inserted under the loggedBody to keep counting the bytes received
+	body := &resp.Body
+	if lb, ok := resp.Body.(*loggedBody); ok {
+		body = &lb.ReadCloser
+	}
+	br := bufio.NewReaderSize(*body, maxSniffedJSONSize)
+	*body = struct {
+		io.Reader
+		io.Closer
+	}{br, *body}
+	peeked, err := br.Peek(maxSniffedJSONSize)
+	if err != io.EOF {
+		// The body is larger than a JSON response of the API, or the read failed and is reported with the report
+		return false
+	}
+	check := struct {
+		Success *bool `json:"success"`
+	}{}
+	return json.Unmarshal(peeked, &check) == nil && check.Success != nil
+}
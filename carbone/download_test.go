@@ -0,0 +1,190 @@
+package carbone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerDownloadResponders mocks Carbone Render sending the report in the render response if direct is set
+// and the request asks for it. The template "missing" fails. It returns the URLs of the requests received.
+func registerDownloadResponders(direct bool) func() []string {
+	var mu sync.Mutex
+	var requests []string
+	httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests = append(requests, req.Method+" "+req.URL.RequestURI())
+		mu.Unlock()
+		switch {
+		case req.Method == "POST" && req.URL.Path == "/render/missing":
+			return newTypedResponse("application/json; charset=utf-8", `{"success": false, "error": "Invalid JSON data"}`), nil
+		case req.Method == "POST" && req.URL.Path == "/render/tpl":
+			if direct && req.URL.Query().Get("download") == "true" {
+				resp := newTypedResponse("application/pdf", "%PDF-1.4")
+				resp.Header.Set("Content-Disposition", `attachment; filename="report.pdf"`)
+				return resp, nil
+			}
+			return newTypedResponse("application/json; charset=utf-8", `{"success": true, "data": {"renderId": "report"}}`), nil
+		case req.Method == "GET" && req.URL.Path == "/render/report":
+			return newTypedResponse("application/pdf", "%PDF-1.4"), nil
+		}
+		return httpmock.NewStringResponse(http.StatusNotFound, ""), nil
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, requests...)
+	}
+}
+
+// newTypedResponse returns a response with the Content-Type contentType, the header is not sent if it is empty.
+// The body of httpmock is read again after io.EOF, it is replaced by a reader stopping at the end like a connection.
+func newTypedResponse(contentType string, body string) *http.Response {
+	resp := httpmock.NewStringResponse(200, body)
+	resp.Body = ioutil.NopCloser(strings.NewReader(body))
+	if contentType != "" {
+		resp.Header.Set("Content-Type", contentType)
+	}
+	return resp
+}
+
+func TestDirectDownload(t *testing.T) {
+	jsonData := `{"data":{},"convertTo":"pdf"}`
+
+	t.Run("Should receive the report in the render response", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		requests := registerDownloadResponders(true)
+		// ----
+		c, err := New(WithToken("token"), WithAPIVersion(5), WithDirectDownload(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := c.Render("tpl", jsonData)
+		if err != nil || string(report) != "%PDF-1.4" {
+			t.Fatal(errors.New("The report is not correct"), err)
+		}
+		result, err := c.RenderWithResult(context.Background(), "tpl", jsonData)
+		if err != nil || !result.DirectDownload || result.RenderID != "" || result.Filename != "report.pdf" || string(result.Report) != "%PDF-1.4" {
+			t.Fatal(errors.New("The result is not correct"), err, result)
+		}
+		var buf bytes.Buffer
+		if n, err := c.RenderTo(context.Background(), "tpl", jsonData, &buf); err != nil || n != 8 || buf.String() != "%PDF-1.4" {
+			t.Error(errors.New("The report should have been copied"), err)
+		}
+		got := requests()
+		if len(got) != 3 || got[0] != "POST /render/tpl?download=true" {
+			t.Error(errors.New("Each report should have been rendered with a single request"), got)
+		}
+	})
+
+	t.Run("Should download the report when the API answers with a renderId", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		requests := registerDownloadResponders(false)
+		// ----
+		c, err := New(WithToken("token"), WithAPIVersion(5), WithDirectDownload(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := c.RenderStream(context.Background(), "tpl", jsonData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Close()
+		if got := requests(); len(got) != 2 || got[0] != "POST /render/tpl?download=true" || got[1] != "GET /render/report" {
+			t.Error(errors.New("The report should have been downloaded with a second request"), got)
+		}
+	})
+
+	t.Run("Should not ask for the report when the direct download is disabled or unsupported", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		requests := registerDownloadResponders(true)
+		// ----
+		disabled, err := New(WithToken("token"), WithAPIVersion(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		unsupported, err := New(WithToken("token"), WithDirectDownload(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []*CSDK{disabled, unsupported} {
+			if report, err := c.Render("tpl", jsonData); err != nil || string(report) != "%PDF-1.4" {
+				t.Fatal(errors.New("The report is not correct"), err)
+			}
+		}
+		if got := requests(); len(got) != 4 || got[0] != "POST /render/tpl" || got[2] != "POST /render/tpl" {
+			t.Error(errors.New("The reports should have been rendered in two steps"), got)
+		}
+	})
+
+	t.Run("Should read the JSON of the API sent without a JSON media type", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		// ----
+		for _, contentType := range []string{"text/plain; charset=utf-8", ""} {
+			contentType := contentType
+			httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+				if req.Method == "GET" {
+					return newTypedResponse("application/pdf", "%PDF-1.4"), nil
+				}
+				return newTypedResponse(contentType, `{"success": true, "data": {"renderId": "report"}}`), nil
+			})
+			for _, version := range []int{4, 5} {
+				c, err := New(WithToken("token"), WithAPIVersion(version), WithDirectDownload(true))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report, err := c.Render("tpl", jsonData); err != nil || string(report) != "%PDF-1.4" {
+					t.Error(errors.New("The report should have been downloaded"), contentType, version, string(report), err)
+				}
+			}
+		}
+	})
+
+	t.Run("Should receive a text report in the render response", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+			return newTypedResponse("text/plain", "name;total"), nil
+		})
+		// ----
+		c, err := New(WithToken("token"), WithAPIVersion(5), WithDirectDownload(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report, err := c.Render("tpl", jsonData); err != nil || string(report) != "name;total" {
+			t.Error(errors.New("The text report should have been returned"), string(report), err)
+		}
+	})
+
+	t.Run("Should return the error of the API", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerDownloadResponders(true)
+		// ----
+		c, err := New(WithToken("token"), WithAPIVersion(5), WithDirectDownload(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var apiErr *APIError
+		if _, err := c.Render("missing", jsonData); !errors.As(err, &apiErr) || err.Error() != "Invalid JSON data" {
+			t.Error(errors.New("The error of the API should have been returned"), err)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package carbone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors returned by the SDK, test them with errors.Is.
+var (
+	// ErrTemplateNotFound is returned when the template does not exist or has been deleted by Carbone Render.
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrUnauthorized is returned when the access token is missing or invalid.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited is returned when Carbone Render rejects the request because of too many requests.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrReportExpired is returned when the report of a renderId has already been downloaded or has expired.
+	ErrReportExpired = errors.New("report expired")
+	// ErrMissingArgument is returned when a required argument is empty.
+	ErrMissingArgument = errors.New("argument is missing")
+	// ErrInvalidArgument is returned when an argument is rejected by the validation of the SDK.
+	ErrInvalidArgument = errors.New("argument is invalid")
+	// ErrUnsupported is returned when a feature is not available with the API version, see Features.
+	ErrUnsupported = errors.New("feature is not supported by the API version")
+)
+
+// MarshalError is returned when the data or the options of a render can not be marshalled to JSON.
+// No request is sent to Carbone Render.
+type MarshalError struct {
+	// Err is the error returned by encoding/json
+	Err error
+}
+
+// Error returns the marshalling error.
+func (e *MarshalError) Error() string {
+	return "Carbone SDK render error: failled to marshal the data: " + e.Err.Error()
+}
+
+// Unwrap returns the error of encoding/json.
+func (e *MarshalError) Unwrap() error {
+	return e.Err
+}
+
+// RenderError is returned by Render when the report of a template path can not be rendered. It tells whether the
+// template was missing and has been uploaded again, use errors.As to read it. The error message is the one of Err.
+type RenderError struct {
+	// TemplateID is the template of the last render
+	TemplateID string
+	// TemplateMissing reports whether Carbone Render answered the template does not exist
+	TemplateMissing bool
+	// Reuploaded reports whether the template has been uploaded and the report rendered again
+	Reuploaded bool
+	// Err is the error of the last request, often an *APIError
+	Err error
+}
+
+// Error returns the error of the last request.
+func (e *RenderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the last request.
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// maxErrorBodySize is the maximum number of bytes of the response body kept in an APIError.
+const maxErrorBodySize = 1024
+
+// APIError describes a request rejected by Carbone Render, it is returned when the status code is not successful
+// or when the API answers "success": false. Use errors.As to read it and errors.Is to compare it with the sentinel errors.
+type APIError struct {
+	// Method and Path of the request
+	Method string
+	Path   string
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Reason and Message are the "error" and "message" fields of the JSON response, if any
+	Reason  string
+	Message string
+	// Body is the beginning of the response body
+	Body string
+	// RequestID is the identifier of the request returned by the server, if any
+	RequestID string
+}
+
+// Error returns the error sent by Carbone Render, or the status code if the response has no error message.
+func (e *APIError) Error() string {
+	if e.StatusCode == http.StatusOK || e.StatusCode == http.StatusNotFound {
+		// The API answered with a JSON error, return it as it is
+		if e.Reason != "" {
+			return e.Reason
+		}
+		if e.Message != "" {
+			return e.Message
+		}
+	}
+	if e.StatusCode == http.StatusOK {
+		return "Carbone SDK request error: the API returned success false"
+	}
+	msg := fmt.Sprintf("Carbone SDK request error status code %d", e.StatusCode)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Is reports whether the error matches one of the sentinel errors of the package.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrReportExpired:
+		return e.isReportDownload() && e.StatusCode == http.StatusNotFound
+	case ErrTemplateNotFound:
+		if e.isReportDownload() {
+			return false
+		}
+		// The API may answer with a status 200 and one of the following errors:
+		// - Error while rendering template Error: ENOENT:File not found
+		// - Error while rendering template Error: 404 Not Found
+		return e.StatusCode == http.StatusNotFound || strings.Contains(e.Reason, "ENOENT") || strings.Contains(e.Reason, "404 Not Found")
+	}
+	return false
+}
+
+// isReportDownload reports whether the failed request is the download of a report.
+func (e *APIError) isReportDownload() bool {
+	return e.Method == http.MethodGet && strings.HasPrefix(e.Path, "/render/")
+}
+
+// newAPIError creates an APIError from a response, the body is read up to maxErrorBodySize bytes and closed.
+func newAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	cResp := APIResponse{}
+	json.Unmarshal(body, &cResp)
+	return newAPIErrorFromBody(resp, cResp, body)
+}
+
+// newAPIErrorFromBody creates an APIError from a response already parsed as an APIResponse.
+func newAPIErrorFromBody(resp *http.Response, cResp APIResponse, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Reason:     cResp.Error,
+		Message:    cResp.Message,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+	apiErr.Body = string(body)
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Path = resp.Request.URL.Path
+	}
+	return apiErr
+}
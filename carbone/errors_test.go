@@ -0,0 +1,269 @@
+package carbone
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestAPIError(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+
+	t.Run("Should return an APIError describing the failed request", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(401, `{"success": false, "error": "Unauthorized, please provide a valid API key", "message": "Check the Authorization header"}`)
+			resp.Header.Set("X-Request-Id", "req-1234")
+			return resp, nil
+		})
+		// ----
+		_, err := csdk.RenderReport(templateID, jsonData)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatal(errors.New("The error should be an APIError"))
+		}
+		if apiErr.Method != "POST" || apiErr.Path != "/render/"+templateID || apiErr.StatusCode != 401 {
+			t.Error(errors.New("The request is not described by the APIError"))
+		}
+		if apiErr.Reason != "Unauthorized, please provide a valid API key" || apiErr.Message != "Check the Authorization header" {
+			t.Error(errors.New("The JSON error is not parsed"))
+		}
+		if apiErr.RequestID != "req-1234" {
+			t.Error(errors.New("The request ID is missing"))
+		}
+		if !strings.Contains(apiErr.Body, `"success": false`) {
+			t.Error(errors.New("The body is missing"))
+		}
+		if err.Error() != "Carbone SDK request error status code 401: Unauthorized, please provide a valid API key" {
+			t.Error(errors.New("The error message is not correct: " + err.Error()))
+		}
+		if !errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrTemplateNotFound) {
+			t.Error(errors.New("The error should only match ErrUnauthorized"))
+		}
+	})
+
+	t.Run("Should match ErrRateLimited and keep a bounded body", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("DELETE", "https://api.carbone.io/template/"+templateID, httpmock.NewStringResponder(429, strings.Repeat("x", 5000)))
+		// ----
+		_, err := csdk.DeleteTemplate(templateID)
+		if !errors.Is(err, ErrRateLimited) {
+			t.Fatal(errors.New("The error should match ErrRateLimited"))
+		}
+		var apiErr *APIError
+		errors.As(err, &apiErr)
+		if len(apiErr.Body) != maxErrorBodySize {
+			t.Error(errors.New("The body should have been truncated"))
+		}
+		if err.Error() != "Carbone SDK request error status code 429" {
+			t.Error(errors.New("The error message is not correct: " + err.Error()))
+		}
+	})
+
+	t.Run("Should match ErrReportExpired when the report does not exist anymore", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewStringResponder(404, `{"success": false, "error": "File not found"}`))
+		// ----
+		report, err := csdk.GetReport(renderID)
+		if len(report) > 0 {
+			t.Error(errors.New("The JSON error should not be returned as a report"))
+		}
+		if !errors.Is(err, ErrReportExpired) || errors.Is(err, ErrTemplateNotFound) {
+			t.Error(errors.New("The error should only match ErrReportExpired"))
+		}
+	})
+
+	t.Run("Should match ErrReportExpired when the report is empty", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewBytesResponder(200, []byte{}))
+		// ----
+		_, err := csdk.GetReport(renderID)
+		if !errors.Is(err, ErrReportExpired) {
+			t.Error(errors.New("The error should match ErrReportExpired"))
+		}
+	})
+
+	t.Run("Should match ErrTemplateNotFound when Render fails with a templateID", func(t *testing.T) {
+		errorMessage := "Error while rendering template Error: ENOENT:File not found"
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(200, `{"success": false, "error": "`+errorMessage+`"}`))
+		// ----
+		_, err := csdk.Render(templateID, jsonData)
+		if !errors.Is(err, ErrTemplateNotFound) {
+			t.Fatal(errors.New("The error should match ErrTemplateNotFound"))
+		}
+		if err.Error() != errorMessage {
+			t.Error(errors.New("The error message of the API should be returned as it is"))
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != 200 || apiErr.Reason != errorMessage {
+			t.Error(errors.New("The error should be an APIError"))
+		}
+	})
+
+	t.Run("Should not match any sentinel for a data error", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(400, `{"success": false, "error": "Invalid JSON data"}`))
+		// ----
+		_, err := csdk.RenderReport(templateID, jsonData)
+		for _, sentinel := range []error{ErrTemplateNotFound, ErrUnauthorized, ErrRateLimited, ErrReportExpired, ErrMissingArgument} {
+			if errors.Is(err, sentinel) {
+				t.Error(errors.New("The error should not match " + sentinel.Error()))
+			}
+		}
+	})
+
+	t.Run("Should match ErrMissingArgument and keep the error messages", func(t *testing.T) {
+		_, err := csdk.AddTemplate("")
+		if !errors.Is(err, ErrMissingArgument) || err.Error() != "Carbone SDK AddTemplate error: argument is missing: templateFileName" {
+			t.Error(errors.New("AddTemplate should return ErrMissingArgument"))
+		}
+		_, err = csdk.RenderReport(templateID, "")
+		if !errors.Is(err, ErrMissingArgument) || err.Error() != "Carbone SDK RenderReport error: argument is missing: jsonData" {
+			t.Error(errors.New("RenderReport should return ErrMissingArgument"))
+		}
+		_, err = csdk.GetReport("")
+		if !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("GetReport should return ErrMissingArgument"))
+		}
+		_, err = csdk.GetTemplate("")
+		if !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("GetTemplate should return ErrMissingArgument"))
+		}
+		_, err = csdk.DeleteTemplate("")
+		if !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("DeleteTemplate should return ErrMissingArgument"))
+		}
+	})
+}
+
+func TestRenderError(t *testing.T) {
+	templatePath := "./tests/template.test.html"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+	templateID, err := csdk.GenerateTemplateID(templatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should not upload the template again when the render fails for another reason", func(t *testing.T) {
+		errorMessage := "Error while rendering template Error: formatter foo does not exist"
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(200, `{"success": false, "error": "`+errorMessage+`"}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": true, "data": {"templateId": "`+templateID+`"}}`))
+		// ----
+		_, err := csdk.Render(templatePath, jsonData)
+		if err == nil || err.Error() != errorMessage {
+			t.Fatal(errors.New("The error of the API should be returned as it is"), err)
+		}
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || renderErr.TemplateMissing || renderErr.Reuploaded || renderErr.TemplateID != templateID {
+			t.Error(errors.New("The error should tell the template has not been uploaded again"))
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Reason != errorMessage {
+			t.Error(errors.New("The error should wrap the APIError"))
+		}
+		if httpmock.GetTotalCallCount() != 1 {
+			t.Error(errors.New("HTTPMOCH error - the template should not have been uploaded"))
+		}
+	})
+
+	t.Run("Should not upload the template again when the API answers an error status", func(t *testing.T) {
+		for _, status := range []int{400, 500} {
+			// ---- httpmock
+			httpmock.Activate()
+			httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(status, `{"success": false, "error": "bad json"}`))
+			httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": true, "data": {"templateId": "`+templateID+`"}}`))
+			// ----
+			_, err := csdk.Render(templatePath, jsonData)
+			var renderErr *RenderError
+			if !errors.As(err, &renderErr) || renderErr.TemplateMissing || renderErr.Reuploaded || renderErr.TemplateID != templateID {
+				t.Error(errors.New("The error status should be returned as a RenderError"), status, err)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+				t.Error(errors.New("The error should wrap the APIError"), status, err)
+			}
+			if httpmock.GetTotalCallCount() != 1 {
+				t.Error(errors.New("HTTPMOCH error - the template should not have been uploaded"), status)
+			}
+			httpmock.DeactivateAndReset()
+		}
+	})
+
+	t.Run("Should upload the template again when the API answers 404 without a JSON body", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(404, "Not Found"))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": true, "data": {"templateId": "uploaded"}}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/uploaded", httpmock.NewStringResponder(200, `{"success": false, "error": "Invalid JSON data"}`))
+		// ----
+		_, err := csdk.Render(templatePath, jsonData)
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || !renderErr.TemplateMissing || !renderErr.Reuploaded || renderErr.TemplateID != "uploaded" {
+			t.Fatal(errors.New("The error should tell the template has been uploaded again"), err)
+		}
+		if err.Error() != "Invalid JSON data" || errors.Is(err, ErrTemplateNotFound) {
+			t.Error(errors.New("The error of the second render should be returned"), err)
+		}
+		if httpmock.GetTotalCallCount() != 3 {
+			t.Error(errors.New("HTTPMOCH error - the number of requests is invalid"))
+		}
+	})
+
+	t.Run("Should upload the template again when the API answers an error status with ENOENT", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(500, `{"success": false, "error": "Error while rendering template Error: ENOENT:File not found"}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": true, "data": {"templateId": "uploaded"}}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/uploaded", httpmock.NewStringResponder(500, `{"success": false, "error": "Internal error"}`))
+		// ----
+		_, err := csdk.Render(templatePath, jsonData)
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || !renderErr.TemplateMissing || !renderErr.Reuploaded || renderErr.TemplateID != "uploaded" {
+			t.Fatal(errors.New("The error should tell the template has been uploaded again"), err)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 || errors.Is(err, ErrTemplateNotFound) {
+			t.Error(errors.New("The error of the second render should be returned"), err)
+		}
+		if httpmock.GetTotalCallCount() != 3 {
+			t.Error(errors.New("HTTPMOCH error - the number of requests is invalid"))
+		}
+	})
+
+	t.Run("Should report a failed upload", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(200, `{"success": false, "error": "Error while rendering template Error: ENOENT:File not found"}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": false, "error": "Template too large"}`))
+		// ----
+		_, err := csdk.Render(templatePath, jsonData)
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || !renderErr.TemplateMissing || renderErr.Reuploaded || !strings.Contains(err.Error(), "Template too large") {
+			t.Error(errors.New("The failed upload should be returned"), err)
+		}
+	})
+}
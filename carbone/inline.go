@@ -0,0 +1,106 @@
+package carbone
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// RenderInline renders a report from a template sent with the render request, for instance a template supplied by a
+// user for a one-off document. name is the filename of the template, its extension is used by Carbone.
+// With the API version 5, the template is encoded in base64 in the render body and is never stored by Carbone Render.
+// With the older versions, the template is uploaded with a unique templateID, rendered and deleted before the report
+// is downloaded. The template is loaded in memory with the version 5.
+func (csdk *CSDK) RenderInline(ctx context.Context, name string, template io.Reader, req RenderRequest) (report []byte, err error) {
+	ctx, op := csdk.startOperation(ctx, "RenderInline")
+	defer func() { op.end(err) }()
+	if name == "" {
+		return []byte{}, fmt.Errorf("Carbone SDK RenderInline error: %w: name", ErrMissingArgument)
+	}
+	if template == nil {
+		return []byte{}, fmt.Errorf("Carbone SDK RenderInline error: %w: template", ErrMissingArgument)
+	}
+	jsonData, err := marshalRenderRequest(req)
+	if err != nil {
+		return []byte{}, err
+	}
+	if !csdk.dialect().features.InlineTemplates {
+		return csdk.renderEphemeral(ctx, name, template, jsonData)
+	}
+	content, err := ioutil.ReadAll(template)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Carbone SDK RenderInline error: failled to read the template: %w", err)
+	}
+	body, err := inlineRenderBody(content, jsonData)
+	if err != nil {
+		return []byte{}, err
+	}
+	directDownload := csdk.settings().directDownload && csdk.dialect().features.DirectDownload
+	url := csdk.dialect().inlineRenderURL(csdk.apiURL, directDownload)
+	cResp, apiErr, direct, err := csdk.postRender(ctx, "RenderInline", url, body, directDownload)
+	if err != nil {
+		return []byte{}, err
+	}
+	if direct != nil {
+		return readReport("RenderInline", direct)
+	}
+	if apiErr != nil {
+		return []byte{}, apiErr
+	}
+	if cResp.Data.RenderID == "" {
+		return []byte{}, errors.New("Carbone SDK RenderInline error: renderID is empty")
+	}
+	return csdk.GetReportContext(ctx, cResp.Data.RenderID)
+}
+
+// renderEphemeral uploads a template with a unique templateID, renders the report and deletes the template.
+func (csdk *CSDK) renderEphemeral(ctx context.Context, name string, template io.Reader, jsonData string) ([]byte, error) {
+	// A random payload gives a unique templateID: the same template uploaded by another caller is not deleted
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return []byte{}, fmt.Errorf("Carbone SDK RenderInline error: %w", err)
+	}
+	upload, _, apiErr, err := csdk.uploadTemplate(ctx, "AddTemplate", name, template, AddTemplateOptions{Payload: hex.EncodeToString(salt)})
+	if err = resultError(apiErr, err); err != nil {
+		return []byte{}, err
+	}
+	if upload.Data.TemplateID == "" {
+		return []byte{}, errors.New("Carbone SDK RenderInline error: templateID is empty")
+	}
+	templateID := upload.Data.TemplateID
+	cResp, apiErr, err := csdk.renderReport(ctx, templateID, jsonData)
+	// The report does not depend on the template once it is rendered, the template is deleted even if ctx is done
+	del, delErr := csdk.DeleteTemplateContext(context.WithoutCancel(ctx), templateID)
+	if delErr == nil && !del.Success {
+		delErr = fmt.Errorf("the API returned success false: %s", del.Error)
+	}
+	if delErr != nil {
+		csdk.logger.Log(ctx, LevelWarn, "Carbone template not deleted", "templateId", templateID, "error", delErr)
+	}
+	if err != nil {
+		return []byte{}, err
+	}
+	if apiErr != nil {
+		return []byte{}, apiErr
+	}
+	if cResp.Data.RenderID == "" {
+		return []byte{}, errors.New("Carbone SDK RenderInline error: renderID is empty")
+	}
+	return csdk.GetReportContext(ctx, cResp.Data.RenderID)
+}
+
+// inlineRenderBody adds the template encoded in base64 to the JSON body of a render.
+func inlineRenderBody(template []byte, jsonData string) (string, error) {
+	encoded, err := json.Marshal(base64.StdEncoding.EncodeToString(template))
+	if err != nil {
+		return "", &MarshalError{Err: err}
+	}
+	// jsonData is an object containing at least "data"
+	return `{"template":` + string(encoded) + `,` + jsonData[1:], nil
+}
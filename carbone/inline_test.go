@@ -0,0 +1,191 @@
+package carbone
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerInlineResponders mocks Carbone Render. The render of the data {"fail": true} fails.
+// It returns the requests received.
+func registerInlineResponders() func() []string {
+	var mu sync.Mutex
+	var requests []string
+	register := func(method, path string, answer func(req *http.Request) string) {
+		httpmock.RegisterResponder(method, "https://api.carbone.io"+path, func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			mu.Unlock()
+			return httpmock.NewStringResponse(200, answer(req)), nil
+		})
+	}
+	register("POST", "/render/template", func(req *http.Request) string {
+		if template, _ := readInlineBody(req); template != "<html>{d.name}</html>" {
+			return `{"success": false, "error": "invalid template"}`
+		}
+		return `{"success": true, "data": {"renderId": "report"}}`
+	})
+	register("POST", "/template", func(req *http.Request) string {
+		if req.FormValue("payload") == "" {
+			return `{"success": false, "error": "the payload should be unique"}`
+		}
+		return `{"success": true, "data": {"templateId": "ephemeral"}}`
+	})
+	register("POST", "/render/ephemeral", func(req *http.Request) string {
+		if _, fail := readInlineBody(req); fail {
+			return `{"success": false, "error": "Error while rendering template"}`
+		}
+		return `{"success": true, "data": {"renderId": "report"}}`
+	})
+	register("DELETE", "/template/ephemeral", func(req *http.Request) string {
+		return `{"success": true}`
+	})
+	register("GET", "/render/report", func(req *http.Request) string {
+		return "report"
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, requests...)
+	}
+}
+
+// readInlineBody returns the template and the data {"fail": true} of a render body.
+func readInlineBody(req *http.Request) (string, bool) {
+	body := struct {
+		Template string `json:"template"`
+		Data     struct {
+			Fail bool `json:"fail"`
+		} `json:"data"`
+	}{}
+	raw, _ := ioutil.ReadAll(req.Body)
+	json.Unmarshal(raw, &body)
+	template, _ := base64.StdEncoding.DecodeString(body.Template)
+	return string(template), body.Data.Fail
+}
+
+func TestRenderInline(t *testing.T) {
+	template := "<html>{d.name}</html>"
+	req := RenderRequest{Data: map[string]string{"name": "John"}}
+
+	t.Run("Should send the template with the render request", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		requests := registerInlineResponders()
+		// ----
+		c, err := New(WithToken("token"), WithAPIVersion(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := c.RenderInline(context.Background(), "template.html", strings.NewReader(template), req)
+		if err != nil || string(report) != "report" {
+			t.Fatal(errors.New("The report is not correct"), err)
+		}
+		if got := strings.Join(requests(), ", "); got != "POST /render/template, GET /render/report" {
+			t.Error(errors.New("The template should not have been stored"), got)
+		}
+	})
+
+	t.Run("Should upload, render and delete the template with the older versions", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		requests := registerInlineResponders()
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := c.RenderInline(context.Background(), "template.html", strings.NewReader(template), req)
+		if err != nil || string(report) != "report" {
+			t.Fatal(errors.New("The report is not correct"), err)
+		}
+		if got := strings.Join(requests(), ", "); got != "POST /template, POST /render/ephemeral, DELETE /template/ephemeral, GET /render/report" {
+			t.Error(errors.New("The template should have been deleted after the render"), got)
+		}
+	})
+
+	t.Run("Should delete the template when the render fails", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		requests := registerInlineResponders()
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.RenderInline(context.Background(), "template.html", strings.NewReader(template), RenderRequest{Data: map[string]bool{"fail": true}})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || err.Error() != "Error while rendering template" {
+			t.Error(errors.New("The error of the render should have been returned"), err)
+		}
+		if got := strings.Join(requests(), ", "); got != "POST /template, POST /render/ephemeral, DELETE /template/ephemeral" {
+			t.Error(errors.New("The template should have been deleted"), got)
+		}
+	})
+
+	t.Run("Should return the APIError of a failed upload", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerInlineResponders()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": false, "error": "quota exceeded"}`))
+		// ----
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.RenderInline(context.Background(), "template.html", strings.NewReader(template), req)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Reason != "quota exceeded" {
+			t.Error(errors.New("The error of the upload should have been returned as an APIError"), err)
+		}
+		if httpmock.GetTotalCallCount() != 1 {
+			t.Error(errors.New("HTTPMOCH error - the template should not have been rendered"))
+		}
+	})
+
+	t.Run("Should return an error if the renderId is empty", func(t *testing.T) {
+		for _, version := range []int{4, 5} {
+			// ---- httpmock
+			httpmock.Activate()
+			requests := registerInlineResponders()
+			httpmock.RegisterResponder("POST", "https://api.carbone.io/render/template", httpmock.NewStringResponder(200, `{"success": true, "data": {}}`))
+			httpmock.RegisterResponder("POST", "https://api.carbone.io/render/ephemeral", httpmock.NewStringResponder(200, `{"success": true, "data": {}}`))
+			// ----
+			c, err := New(WithToken("token"), WithAPIVersion(version))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.RenderInline(context.Background(), "template.html", strings.NewReader(template), req)
+			if err == nil || err.Error() != "Carbone SDK RenderInline error: renderID is empty" {
+				t.Error(errors.New("The empty renderId should have been reported"), version, err)
+			}
+			for _, request := range requests() {
+				if strings.HasPrefix(request, "GET ") {
+					t.Error(errors.New("The report should not have been downloaded"), version)
+				}
+			}
+			httpmock.DeactivateAndReset()
+		}
+	})
+
+	t.Run("Should return an error if an argument is missing", func(t *testing.T) {
+		if _, err := csdk.RenderInline(context.Background(), "", strings.NewReader(template), req); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("The name is missing"))
+		}
+		if _, err := csdk.RenderInline(context.Background(), "template.html", nil, req); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("The template is missing"))
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package carbone
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a message emitted by the SDK. The values match the log/slog levels.
+type LogLevel int
+
+// Log levels used by the SDK.
+const (
+	LevelDebug LogLevel = -4
+	LevelInfo  LogLevel = 0
+	LevelWarn  LogLevel = 4
+	LevelError LogLevel = 8
+)
+
+// String returns the name of the level.
+func (l LogLevel) String() string {
+	switch {
+	case l < LevelInfo:
+		return "Debug"
+	case l < LevelWarn:
+		return "Info"
+	case l < LevelError:
+		return "Warning"
+	default:
+		return "Error"
+	}
+}
+
+// Logger receives the diagnostics of the SDK.
+// keyvals is a list of alternating keys and values describing the message.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// nopLogger discards every message, it is the default logger of New.
+type nopLogger struct{}
+
+func (nopLogger) Log(context.Context, LogLevel, string, ...interface{}) {}
+
+// NewSlogLogger returns a Logger writing the diagnostics of the SDK to l, slog.Default() if l is nil.
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return slogLogger{l: l}
+}
+
+// slogLogger is the log/slog adapter returned by NewSlogLogger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	s.l.Log(ctx, slog.Level(level), msg, keyvals...)
+}
+
+// writerLogger prints messages as lines of text, as the SDK did before Logger existed.
+// Messages below minLevel are discarded.
+type writerLogger struct {
+	w        io.Writer
+	minLevel LogLevel
+}
+
+func (l writerLogger) Log(_ context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if level < l.minLevel {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("Carbone SDK " + level.String() + ": " + msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&sb, " %v=%v", keyvals[i], keyvals[i+1])
+	}
+	fmt.Fprintln(l.w, sb.String())
+}
+
+// redactedToken replaces the access token in the logs.
+const redactedToken = "[REDACTED]"
+
+// redactHeader returns a copy of h safe to log: the bearer token of the "Authorization" header,
+// any header value containing the access token and the query of the webhook URL are redacted.
+func redactHeader(h http.Header, token string) http.Header {
+	redacted := make(http.Header, len(h))
+	for k, values := range h {
+		for _, v := range values {
+			if k == "Authorization" && strings.HasPrefix(v, "Bearer ") {
+				v = "Bearer " + redactedToken
+			} else if token != "" && strings.Contains(v, token) {
+				v = strings.ReplaceAll(v, token, redactedToken)
+			}
+			if k == "Carbone-Webhook-Url" {
+				v = redactURLQuery(v)
+			}
+			redacted.Add(k, v)
+		}
+	}
+	return redacted
+}
+
+// redactURLQuery returns rawURL without the values of its query, they may contain a secret.
+func redactURLQuery(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedToken
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redactedToken
+	}
+	return u.String()
+}
+
+// requestStats collects the attempts and the bytes sent by a request.
+type requestStats struct {
+	attempts      int
+	contentLength int64
+	sent          *countingReader
+}
+
+// bytesSent returns the number of bytes of the last attempt, the Content-Length is used if the body has not been read.
+func (s *requestStats) bytesSent() int64 {
+	if s.sent == nil {
+		return 0
+	}
+	if s.sent.n == 0 && s.contentLength > 0 {
+		return s.contentLength
+	}
+	return s.sent.n
+}
+
+// countingReader counts the bytes read from a request or a response body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// loggedBody is a response body logging the request once it is closed, when the duration
+// and the bytes received are known.
+type loggedBody struct {
+	countingReader
+	ctx     context.Context
+	logger  Logger
+	start   time.Time
+	keyvals []interface{}
+	once    sync.Once
+}
+
+// addIDs appends the IDs returned by Carbone Render to the log of the request.
+func (b *loggedBody) addIDs(data APIResponseData) {
+	if data.TemplateID != "" {
+		b.keyvals = append(b.keyvals, "templateId", data.TemplateID)
+	}
+	if data.RenderID != "" {
+		b.keyvals = append(b.keyvals, "renderId", data.RenderID)
+	}
+}
+
+func (b *loggedBody) Close() error {
+	err := b.countingReader.Close()
+	b.once.Do(func() {
+		keyvals := append(b.keyvals, "duration", time.Since(b.start), "bytesReceived", b.n)
+		b.logger.Log(b.ctx, LevelDebug, "Carbone request", keyvals...)
+	})
+	return err
+}
+
+// requestKeyvals describes a request for the logs, the IDs are read from the path.
+func requestKeyvals(req *http.Request) []interface{} {
+	keyvals := []interface{}{"method", req.Method, "path", req.URL.Path}
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-1] == "" {
+		return keyvals
+	}
+	id := parts[len(parts)-1]
+	switch {
+	case parts[len(parts)-2] == "template":
+		keyvals = append(keyvals, "templateId", id)
+	case parts[len(parts)-2] == "render" && req.Method == http.MethodGet:
+		keyvals = append(keyvals, "renderId", id)
+	case parts[len(parts)-2] == "render":
+		keyvals = append(keyvals, "templateId", id)
+	}
+	return keyvals
+}
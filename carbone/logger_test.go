@@ -0,0 +1,146 @@
+package carbone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// newSlogSDK returns a CSDK logging every message as JSON lines into buf
+func newSlogSDK(t *testing.T, buf *bytes.Buffer, opts ...Option) *CSDK {
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c, err := New(append([]Option{WithToken("secret-token"), WithLogger(NewSlogLogger(logger))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// logEntries parses the JSON lines written by slog
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	entries := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLogger(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+
+	t.Run("Should log each request with slog and redact the access token", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := newSlogSDK(t, &buf)
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`))
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewStringResponder(200, "<xml>File Content</xml>"))
+		// ----
+		if _, err := c.Render(templateID, jsonData); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buf.String(), "secret-token") {
+			t.Fatal(errors.New("The access token has been logged: " + buf.String()))
+		}
+		entries := logEntries(t, &buf)
+		if len(entries) != 2 {
+			t.Fatal(errors.New("Each request should have been logged once: " + buf.String()))
+		}
+		render, download := entries[0], entries[1]
+		if render["level"] != "DEBUG" || render["msg"] != "Carbone request" || render["method"] != "POST" || render["path"] != "/render/"+templateID {
+			t.Error(errors.New("The render request is not logged: " + buf.String()))
+		}
+		if render["status"] != float64(200) || render["attempts"] != float64(1) || render["bytesSent"] != float64(len(jsonData)) {
+			t.Error(errors.New("The status, the attempts or the bytes sent are not logged"))
+		}
+		if render["templateId"] != templateID || render["renderId"] != renderID {
+			t.Error(errors.New("The IDs of the render are not logged"))
+		}
+		if _, ok := render["duration"]; !ok {
+			t.Error(errors.New("The duration is not logged"))
+		}
+		headers, _ := render["headers"].(map[string]interface{})
+		if auth, _ := headers["Authorization"].([]interface{}); len(auth) != 1 || auth[0] != "Bearer [REDACTED]" {
+			t.Error(errors.New("The Authorization header is not redacted"))
+		}
+		if download["method"] != "GET" || download["renderId"] != renderID || download["bytesReceived"] != float64(len("<xml>File Content</xml>")) {
+			t.Error(errors.New("The download is not logged: " + buf.String()))
+		}
+	})
+
+	t.Run("Should log the retries and the number of attempts", func(t *testing.T) {
+		srv, _ := newFlakyServer(t, 1, http.StatusBadGateway, func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"success": true, "data": {}}`))
+		})
+		defer srv.Close()
+		var buf bytes.Buffer
+		c := newSlogSDK(t, &buf, WithBaseURL(srv.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, RetryableStatusCodes: []int{http.StatusBadGateway}}))
+		if _, err := c.DeleteTemplateContext(context.Background(), templateID); err != nil {
+			t.Fatal(err)
+		}
+		entries := logEntries(t, &buf)
+		if len(entries) != 2 || entries[0]["level"] != "INFO" || entries[0]["msg"] != "Carbone request retried" || entries[0]["status"] != float64(http.StatusBadGateway) {
+			t.Fatal(errors.New("The retry is not logged: " + buf.String()))
+		}
+		if entries[1]["attempts"] != float64(2) || entries[1]["templateId"] != templateID {
+			t.Error(errors.New("The attempts are not logged: " + buf.String()))
+		}
+	})
+
+	t.Run("Should log the requests failing without response", func(t *testing.T) {
+		var buf bytes.Buffer
+		c := newSlogSDK(t, &buf, WithBaseURL("http://127.0.0.1:1"))
+		if _, err := c.DeleteTemplateContext(context.Background(), templateID); err == nil {
+			t.Fatal(errors.New("The request should have failed"))
+		}
+		entries := logEntries(t, &buf)
+		if len(entries) != 1 || entries[0]["msg"] != "Carbone request failed" || entries[0]["error"] == nil {
+			t.Error(errors.New("The failure is not logged: " + buf.String()))
+		}
+	})
+
+	t.Run("Should redact the token of every header", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("Authorization", "Bearer secret-token")
+		h.Set("X-Proxy", "token=secret-token")
+		h.Set("carbone-version", "4")
+		h.Set("carbone-webhook-url", "https://example.com/webhook?key=value")
+		redacted := redactHeader(h, "secret-token")
+		if redacted.Get("Authorization") != "Bearer [REDACTED]" || redacted.Get("X-Proxy") != "token=[REDACTED]" || redacted.Get("carbone-version") != "4" ||
+			redacted.Get("carbone-webhook-url") != "https://example.com/webhook?[REDACTED]" {
+			t.Error(errors.New("The headers are not redacted"))
+		}
+		if h.Get("Authorization") != "Bearer secret-token" {
+			t.Error(errors.New("The headers of the request have been modified"))
+		}
+	})
+
+	t.Run("Should print only the warnings with the legacy constructor", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := writerLogger{w: &buf, minLevel: LevelWarn}
+		l.Log(context.Background(), LevelDebug, "Carbone request")
+		l.Log(context.Background(), LevelInfo, "Carbone request retried")
+		l.Log(context.Background(), LevelWarn, "warning")
+		if buf.String() != "Carbone SDK Warning: warning\n" {
+			t.Error(errors.New("Only the warnings should have been printed: " + buf.String()))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package carbone
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the http.RoundTripper sending the requests to Carbone Render, for instance to authenticate
+// to a corporate proxy, sign the requests or measure them. It must not modify the request, clone it instead.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an http.RoundTripper implemented by a function, to write a Middleware easily.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware adds middlewares to the chain of the CSDK, like Use.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *options) error {
+		o.middlewares = append(o.middlewares, middlewares...)
+		return nil
+	}
+}
+
+// Use appends middlewares to the chain sending the requests. Each request goes through the SDK middleware setting
+// the "Authorization" and "carbone-version" headers, then through the middlewares in the order they were added,
+// and is finally sent by the transport of the HTTP client. The requests in progress keep the previous chain.
+func (csdk *CSDK) Use(middlewares ...Middleware) {
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
+	// The chain is copied, the requests in progress and the clones of the CSDK read the previous one
+	chain := csdk.middlewares[:len(csdk.middlewares):len(csdk.middlewares)]
+	for _, mw := range middlewares {
+		if mw != nil {
+			chain = append(chain, mw)
+		}
+	}
+	csdk.middlewares = chain
+}
+
+// carboneHeadersMiddleware is the first middleware of the chain, it authenticates the request and selects the Carbone version.
+func (csdk *CSDK) carboneHeadersMiddleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// A RoundTripper must not modify the request of the caller
+		r := req.Clone(req.Context())
+		csdk.setCarboneHeaders(r.Header)
+		return next.RoundTrip(r)
+	})
+}
+
+// setCarboneHeaders sets the headers of the SDK middleware.
+func (csdk *CSDK) setCarboneHeaders(h http.Header) {
+	current := csdk.settings()
+	// User Api Token
+	h.Set("Authorization", "Bearer "+current.apiAccessToken)
+	h.Set("carbone-version", current.apiVersion)
+}
+
+// httpClient returns the client sending a request through the middleware chain.
+// The chain is built for each request, a nil transport is resolved to http.DefaultTransport when the request is sent.
+func (csdk *CSDK) httpClient() *http.Client {
+	transport := csdk.apiHTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	middlewares := csdk.settings().middlewares
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+	transport = csdk.carboneHeadersMiddleware(transport)
+	client := *csdk.apiHTTPClient
+	client.Transport = transport
+	return &client
+}
@@ -0,0 +1,137 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordMiddleware appends its name and the "Authorization" header it receives to calls
+func recordMiddleware(name string, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+":"+req.Header.Get("Authorization"))
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// jsonTransport answers body to every request and records the headers of the last request
+func jsonTransport(status int, body string, header *http.Header) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*header = req.Header.Clone()
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+
+	t.Run("Should call the SDK middleware, the middlewares in order, then the transport of the client", func(t *testing.T) {
+		var calls []string
+		var header http.Header
+		client := &http.Client{Transport: jsonTransport(200, `{"success": true}`, &header)}
+		c, err := New(WithToken("token"), WithHTTPClient(client), WithMiddleware(recordMiddleware("first", &calls)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Use(recordMiddleware("second", &calls))
+		if _, err := c.DeleteTemplate(templateID); err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) != 2 || calls[0] != "first:Bearer token" || calls[1] != "second:Bearer token" {
+			t.Error(errors.New("The middlewares have not been called in order after the SDK middleware"), calls)
+		}
+		if header.Get("Authorization") != "Bearer token" || header.Get("carbone-version") != "4" {
+			t.Error(errors.New("The SDK headers have not been sent"))
+		}
+	})
+
+	t.Run("Should let a middleware add or override headers without modifying the request", func(t *testing.T) {
+		var header http.Header
+		var original *http.Request
+		c, err := New(WithToken("token"), WithHTTPClient(&http.Client{Transport: jsonTransport(200, `{"success": true}`, &header)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Records the request passed to the next middleware
+		c.Use(func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				original = req
+				return next.RoundTrip(req)
+			})
+		})
+		c.Use(func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				r := req.Clone(req.Context())
+				r.Header.Set("Proxy-Authorization", "Basic cHJveHk6cHJveHk=")
+				r.Header.Set("carbone-version", "5")
+				return next.RoundTrip(r)
+			})
+		})
+		if _, err := c.DeleteTemplate(templateID); err != nil {
+			t.Fatal(err)
+		}
+		if header.Get("Proxy-Authorization") == "" || header.Get("carbone-version") != "5" {
+			t.Error(errors.New("The headers of the middleware have not been sent"))
+		}
+		if original == nil || original.Header.Get("Proxy-Authorization") != "" {
+			t.Error(errors.New("The request of the previous middleware has been modified"))
+		}
+	})
+
+	t.Run("Should read the access token when the request is sent", func(t *testing.T) {
+		var header http.Header
+		c, err := New(WithToken("token"), WithHTTPClient(&http.Client{Transport: jsonTransport(200, `{"success": true}`, &header)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetAccessToken("new-token")
+		c.SetAPIVersion(3)
+		if _, err := c.DeleteTemplate(templateID); err != nil {
+			t.Fatal(err)
+		}
+		if header.Get("Authorization") != "Bearer new-token" || header.Get("carbone-version") != "3" {
+			t.Error(errors.New("The headers of the SDK middleware are outdated"))
+		}
+	})
+
+	t.Run("Should send each retry through the middlewares", func(t *testing.T) {
+		var calls []string
+		srv, _ := newFlakyServer(t, 1, http.StatusBadGateway, func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"success": true}`))
+		})
+		defer srv.Close()
+		c := newRetrySDK(t, srv.URL, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, RetryableStatusCodes: []int{http.StatusBadGateway}})
+		c.Use(recordMiddleware("mw", &calls), nil)
+		if _, err := c.DeleteTemplateContext(context.Background(), templateID); err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) != 2 {
+			t.Error(errors.New("Each attempt should have gone through the middleware"), calls)
+		}
+	})
+
+	t.Run("Should return the error of a middleware", func(t *testing.T) {
+		c, err := New(WithToken("token"), WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("signature failed")
+			})
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.DeleteTemplate(templateID); err == nil || !strings.Contains(err.Error(), "signature failed") {
+			t.Error(errors.New("The error of the middleware should have been returned"))
+		}
+	})
+}
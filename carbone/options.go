@@ -0,0 +1,228 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultAPIURL     = "https://api.carbone.io"
+	defaultAPIVersion = 4
+	defaultTimeOut    = time.Second * 60
+)
+
+// Option configures a CSDK created by New.
+type Option func(*options) error
+
+// options collects the settings passed to New before the CSDK is built.
+type options struct {
+	accessToken    string
+	apiURL         string
+	apiVersion     int
+	headers        map[string]string
+	httpClient     *http.Client
+	timeOut        *time.Duration
+	logger         Logger
+	userAgent      string
+	retryPolicy    RetryPolicy
+	middlewares    []Middleware
+	rateLimit      RateLimit
+	templateCache  TemplateCache
+	directDownload bool
+	// OpenTelemetry providers, see telemetry.go
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// WithToken sets the Carbone Render access token. It takes precedence over the "CARBONE_TOKEN" env variable.
+func WithToken(token string) Option {
+	return func(o *options) error {
+		o.accessToken = token
+		return nil
+	}
+}
+
+// WithBaseURL sets the URL of the Carbone Render API, for instance an on-premise server.
+// It takes precedence over the "CARBONE_URL" env variable.
+func WithBaseURL(rawURL string) Option {
+	return func(o *options) error {
+		apiURL, err := parseBaseURL(rawURL)
+		if err != nil {
+			return err
+		}
+		o.apiURL = apiURL
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests to Carbone Render.
+// The client timeout is kept unless WithTimeout is passed too.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) error {
+		if client == nil {
+			return errors.New("Carbone SDK WithHTTPClient error: the HTTP client is nil")
+		}
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit of each request sent to Carbone Render, 0 means no timeout. The default is 60 seconds.
+func WithTimeout(timeOut time.Duration) Option {
+	return func(o *options) error {
+		if timeOut < 0 {
+			return fmt.Errorf("Carbone SDK WithTimeout error: the timeout must be positive, got %v", timeOut)
+		}
+		o.timeOut = &timeOut
+		return nil
+	}
+}
+
+// WithAPIVersion sets the Carbone Render version requested. The default is the version 4.
+func WithAPIVersion(version int) Option {
+	return func(o *options) error {
+		if version <= 0 {
+			return fmt.Errorf("Carbone SDK WithAPIVersion error: the version must be greater than 0, got %d", version)
+		}
+		o.apiVersion = version
+		return nil
+	}
+}
+
+// WithHeaders sets custom Carbone headers injected into every request, like SetAPIHeaders.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) error {
+		o.headers = make(map[string]string, len(headers))
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+		return nil
+	}
+}
+
+// WithLogger sets the logger receiving the diagnostics of the SDK. By default nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(o *options) error {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+		o.logger = logger
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests sent to Carbone Render.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithRetryPolicy sets how failed requests are sent again, see DefaultRetryPolicy. By default requests are sent once.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		o.retryPolicy = policy
+		return nil
+	}
+}
+
+// New is a constructor and return a new instance of CSDK configured by opts.
+// If WithToken or WithBaseURL are not passed, the access token and the API URL are read from
+// the "CARBONE_TOKEN" and "CARBONE_URL" env variables.
+func New(opts ...Option) (*CSDK, error) {
+	o := options{
+		accessToken: os.Getenv("CARBONE_TOKEN"),
+		apiVersion:  defaultAPIVersion,
+		logger:      nopLogger{},
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if envURL := os.Getenv("CARBONE_URL"); o.apiURL == "" && envURL != "" {
+		// The env variable is read only if WithBaseURL is not passed
+		apiURL, err := parseBaseURL(envURL)
+		if err != nil {
+			return nil, err
+		}
+		o.apiURL = apiURL
+	}
+	if o.apiURL == "" {
+		o.apiURL = defaultAPIURL
+	}
+	timeOut := defaultTimeOut
+	if o.timeOut != nil {
+		timeOut = *o.timeOut
+	}
+	var httpClient *http.Client
+	if o.httpClient == nil {
+		httpClient = &http.Client{Timeout: timeOut}
+	} else {
+		// Copy the client to never mutate the one owned by the caller
+		c := *o.httpClient
+		if o.timeOut != nil {
+			c.Timeout = timeOut
+		}
+		httpClient = &c
+		timeOut = c.Timeout
+	}
+	if o.accessToken == "" {
+		o.logger.Log(context.Background(), LevelWarn, `Cloud API access token and "CARBONE_TOKEN" env variable are missing`)
+	}
+	tel, err := newTelemetry(o.tracerProvider, o.meterProvider, o.propagator)
+	if err != nil {
+		return nil, fmt.Errorf("Carbone SDK error: failled to create the OpenTelemetry instruments: %w", err)
+	}
+	csdk := &CSDK{
+		apiVersion:     strconv.Itoa(o.apiVersion),
+		apiHeaders:     o.headers,
+		apiAccessToken: o.accessToken,
+		apiURL:         o.apiURL,
+		apiTimeOut:     timeOut,
+		apiHTTPClient:  httpClient,
+		apiUserAgent:   o.userAgent,
+		retryPolicy:    o.retryPolicy,
+		logger:         o.logger,
+		telemetry:      tel,
+		limiter:        newRateLimiter(o.rateLimit),
+		templateCache:  o.templateCache,
+		directDownload: o.directDownload,
+	}
+	csdk.Use(o.middlewares...)
+	return csdk, nil
+}
+
+// parseBaseURL validates the URL of the Carbone Render API and removes the trailing slash.
+func parseBaseURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("Carbone SDK error: invalid API URL %q: %w", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("Carbone SDK error: invalid API URL %q: an absolute http or https URL is expected", rawURL)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("Carbone SDK error: invalid API URL %q: query and fragment are not allowed", rawURL)
+	}
+	return strings.TrimRight(rawURL, "/"), nil
+}
@@ -0,0 +1,206 @@
+package carbone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// memoryLogger stores the messages of the SDK for assertions
+type memoryLogger struct {
+	messages []string
+}
+
+func (l *memoryLogger) Log(_ context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	l.messages = append(l.messages, level.String()+": "+msg)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Should create a default config", func(t *testing.T) {
+		c, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.apiURL != "https://api.carbone.io" {
+			t.Error(errors.New("Default URL not valid"))
+		}
+		if c.apiVersion != "4" {
+			t.Error(errors.New("Default version not valid"))
+		}
+		if c.apiHTTPClient.Timeout != time.Second*60 {
+			t.Error(errors.New("Default timeout not valid"))
+		}
+	})
+
+	t.Run("Should apply every option", func(t *testing.T) {
+		headers := map[string]string{"carbone-template-delete-after": "86400"}
+		c, err := New(
+			WithToken("secret-token"),
+			WithBaseURL("https://test.carbone.io/"),
+			WithTimeout(time.Second*5),
+			WithAPIVersion(3),
+			WithHeaders(headers),
+			WithUserAgent("my-app/1.0"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The headers are copied
+		headers["carbone-template-delete-after"] = "1"
+		if c.apiAccessToken != "secret-token" {
+			t.Error(errors.New("API Key differents"))
+		}
+		if c.apiURL != "https://test.carbone.io" {
+			t.Error(errors.New("URL differents: " + c.apiURL))
+		}
+		if c.apiHTTPClient.Timeout != time.Second*5 || c.apiTimeOut != time.Second*5 {
+			t.Error(errors.New("Timeout differents"))
+		}
+		if v, _ := c.GetAPIVersion(); v != 3 {
+			t.Error(errors.New("Version differents"))
+		}
+		if c.apiHeaders["carbone-template-delete-after"] != "86400" {
+			t.Error(errors.New("Headers differents"))
+		}
+		if c.apiUserAgent != "my-app/1.0" {
+			t.Error(errors.New("User agent differents"))
+		}
+	})
+
+	t.Run("Should prefer options over environment variables", func(t *testing.T) {
+		os.Setenv("CARBONE_TOKEN", "env-token")
+		os.Setenv("CARBONE_URL", "https://env.test.carbone.io")
+		defer os.Unsetenv("CARBONE_TOKEN")
+		defer os.Unsetenv("CARBONE_URL")
+		c, err := New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.apiAccessToken != "env-token" || c.apiURL != "https://env.test.carbone.io" {
+			t.Error(errors.New("The environment variables are ignored"))
+		}
+		c, err = New(WithToken("option-token"), WithBaseURL("https://option.test.carbone.io"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.apiAccessToken != "option-token" || c.apiURL != "https://option.test.carbone.io" {
+			t.Error(errors.New("The options must take precedence over the environment variables"))
+		}
+	})
+
+	t.Run("Should not mutate a custom HTTP client", func(t *testing.T) {
+		client := &http.Client{Timeout: time.Second * 10}
+		c, err := New(WithHTTPClient(client))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.apiHTTPClient.Timeout != time.Second*10 {
+			t.Error(errors.New("The timeout of the custom client should be kept"))
+		}
+		c, err = New(WithHTTPClient(client), WithTimeout(time.Second*2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.apiHTTPClient.Timeout != time.Second*2 {
+			t.Error(errors.New("WithTimeout should override the timeout of the custom client"))
+		}
+		if client.Timeout != time.Second*10 {
+			t.Error(errors.New("The custom client has been mutated"))
+		}
+	})
+
+	t.Run("Should return an error for invalid options", func(t *testing.T) {
+		invalids := map[string]Option{
+			"malformed URL":    WithBaseURL("://api.carbone.io"),
+			"relative URL":     WithBaseURL("api.carbone.io"),
+			"unsupported URL":  WithBaseURL("ftp://api.carbone.io"),
+			"URL with query":   WithBaseURL("https://api.carbone.io?key=1"),
+			"negative timeout": WithTimeout(-time.Second),
+			"invalid version":  WithAPIVersion(0),
+			"nil HTTP client":  WithHTTPClient(nil),
+		}
+		for name, opt := range invalids {
+			c, err := New(opt)
+			if err == nil || c != nil {
+				t.Error(errors.New("Test failled: " + name + " should have returned an error"))
+			}
+		}
+	})
+
+	t.Run("Should return an error for an invalid CARBONE_URL", func(t *testing.T) {
+		os.Setenv("CARBONE_URL", "not an url")
+		defer os.Unsetenv("CARBONE_URL")
+		if _, err := NewCarboneSDK("token"); err == nil {
+			t.Error(errors.New("Test failled: NewCarboneSDK should have returned an error"))
+		}
+	})
+
+	t.Run("Should ignore an invalid CARBONE_URL when the URL is passed", func(t *testing.T) {
+		os.Setenv("CARBONE_URL", "localhost:4000")
+		defer os.Unsetenv("CARBONE_URL")
+		c, err := New(WithBaseURL("https://on-premise.test.carbone.io"))
+		if err != nil || c.apiURL != "https://on-premise.test.carbone.io" {
+			t.Error(errors.New("Test failled: New should have used the URL passed"), err)
+		}
+		c, err = NewCarboneSDK("token", "https://on-premise.test.carbone.io")
+		if err != nil || c.apiURL != "https://on-premise.test.carbone.io" {
+			t.Error(errors.New("Test failled: NewCarboneSDK should have used the URL passed"), err)
+		}
+	})
+
+	t.Run("Should warn through the logger when the access token is missing", func(t *testing.T) {
+		logger := &memoryLogger{}
+		if _, err := New(WithLogger(logger)); err != nil {
+			t.Fatal(err)
+		}
+		if len(logger.messages) != 1 || !strings.HasPrefix(logger.messages[0], "Warning: ") {
+			t.Error(errors.New("The warning has not been logged"))
+		}
+		logger = &memoryLogger{}
+		if _, err := New(WithLogger(logger), WithToken("token")); err != nil {
+			t.Fatal(err)
+		}
+		if len(logger.messages) != 0 {
+			t.Error(errors.New("Nothing should have been logged"))
+		}
+	})
+
+	t.Run("Should print the legacy warning", func(t *testing.T) {
+		var buf bytes.Buffer
+		writerLogger{w: &buf}.Log(context.Background(), LevelWarn, `Cloud API access token and "CARBONE_TOKEN" env variable are missing`)
+		if buf.String() != "Carbone SDK Warning: Cloud API access token and \"CARBONE_TOKEN\" env variable are missing\n" {
+			t.Error(errors.New("The warning is different: " + buf.String()))
+		}
+	})
+
+	t.Run("Should send the user agent", func(t *testing.T) {
+		c, err := New(WithToken("token"), WithUserAgent("my-app/1.0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("DELETE", "https://api.carbone.io/template/1234", func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("User-Agent") != "my-app/1.0" {
+				return httpmock.NewStringResponse(500, "User agent missing"), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success" : true}`), nil
+		})
+		// ----
+		resp, err := c.DeleteTemplate("1234")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Success == false {
+			t.Error(errors.New("The user agent has not been sent"))
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitPause is the time requests are paused after a 429 response without "Retry-After" header.
+const defaultRateLimitPause = time.Second
+
+// RateLimit configures how fast requests are sent to Carbone Render. The zero value disables the limits.
+// Requests exceeding the limits wait for their turn, until their context is done.
+type RateLimit struct {
+	// RequestsPerSecond is the rate of the token bucket, 0 means no rate limit.
+	RequestsPerSecond float64
+	// Burst is the number of requests which can be sent at once, 1 if 0.
+	Burst int
+	// MaxInFlight is the maximum number of requests in progress at the same time, 0 means no limit. A request is in
+	// progress until its response body is closed.
+	MaxInFlight int
+}
+
+// WithRateLimit sets the rate limit of the CSDK, see RateLimit. By default requests are not limited.
+func WithRateLimit(limit RateLimit) Option {
+	return func(o *options) error {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+		o.rateLimit = limit
+		return nil
+	}
+}
+
+// SetRateLimit set the rate limit of the CSDK, see RateLimit. The zero value disables the limits.
+// The requests in progress keep the previous limits.
+func (csdk *CSDK) SetRateLimit(limit RateLimit) error {
+	if err := limit.validate(); err != nil {
+		return err
+	}
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
+	csdk.limiter = newRateLimiter(limit)
+	return nil
+}
+
+// validate returns an error if a field of the limit is out of range.
+func (l RateLimit) validate() error {
+	if l.RequestsPerSecond < 0 || l.Burst < 0 || l.MaxInFlight < 0 {
+		return errors.New("Carbone SDK WithRateLimit error: RequestsPerSecond, Burst and MaxInFlight must be positive")
+	}
+	return nil
+}
+
+// rateLimiter is a token bucket and a semaphore shared by the requests of a CSDK.
+// All requests are paused when Carbone Render answers 429.
+type rateLimiter struct {
+	rate     float64
+	burst    float64
+	inFlight chan struct{}
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// newRateLimiter returns the limiter of limit, nil if it is disabled.
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit == (RateLimit{}) {
+		return nil
+	}
+	l := &rateLimiter{rate: limit.RequestsPerSecond, burst: float64(limit.Burst)}
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	l.tokens = l.burst
+	if limit.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return l
+}
+
+// wait blocks until a request can be sent or ctx is done. release must be called once the response body is closed.
+func (l *rateLimiter) wait(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release = func() {}
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			release = func() { <-l.inFlight }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err := sleepContext(ctx, l.reserve()); err != nil {
+		l.cancel()
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// releasingBody is a response body which gives back the in-flight slot of its request when it is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the body and releases the slot once.
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// reserve takes a token and returns the time to wait before sending the request.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	var d time.Duration
+	if l.rate > 0 {
+		if !l.last.IsZero() {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if l.tokens > l.burst {
+				l.tokens = l.burst
+			}
+		}
+		l.last = now
+		// The token may be borrowed, the next requests wait longer
+		l.tokens--
+		if l.tokens < 0 {
+			d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+	}
+	if pause := l.pausedUntil.Sub(now); pause > d {
+		d = pause
+	}
+	return d
+}
+
+// cancel gives back the token of a request which has not been sent.
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate > 0 {
+		l.tokens++
+	}
+}
+
+// observe pauses all requests if resp is a 429, until the "Retry-After" date. It returns the pause.
+func (l *rateLimiter) observe(resp *http.Response) time.Duration {
+	if l == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+	pause, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if !ok {
+		pause = defaultRateLimitPause
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(pause); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	return pause
+}
@@ -0,0 +1,166 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+
+	t.Run("Should space the requests according to the rate", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"success": true}`))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithRateLimit(RateLimit{RequestsPerSecond: 20, Burst: 2}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		for i := 0; i < 6; i++ {
+			if _, err := c.DeleteTemplate(templateID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 2 requests are sent at once, the 4 others wait 50ms each
+		if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+			t.Error(errors.New("The requests have not been rate limited"), elapsed)
+		}
+	})
+
+	t.Run("Should cap the number of requests in flight", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			w.Write([]byte(`{"success": true}`))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithRateLimit(RateLimit{MaxInFlight: 2}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := c.DeleteTemplate(templateID); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+			t.Error(errors.New("The number of requests in flight is not capped"), max)
+		}
+	})
+
+	t.Run("Should keep the slot of a streamed report until its body is closed", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == "GET" {
+				w.Write([]byte("report"))
+				return
+			}
+			w.Write([]byte(`{"success": true}`))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithRateLimit(RateLimit{MaxInFlight: 1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := c.GetReportStream(context.Background(), "renderID")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := c.DeleteTemplateContext(ctx, templateID); !errors.Is(err, context.DeadlineExceeded) {
+			t.Error(errors.New("The request should have waited for the report to be closed"), err)
+		}
+		report.Close()
+		if _, err := c.DeleteTemplate(templateID); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Should stop waiting when the context is done", func(t *testing.T) {
+		c, err := New(WithToken("token"), WithBaseURL("http://127.0.0.1:1"), WithRateLimit(RateLimit{MaxInFlight: 1}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Take the only slot
+		release, err := c.limiter.wait(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := c.DeleteTemplateContext(ctx, templateID); !errors.Is(err, context.DeadlineExceeded) {
+			t.Error(errors.New("The request should have stopped waiting"), err)
+		}
+	})
+
+	t.Run("Should pause every request after a 429 until Retry-After", func(t *testing.T) {
+		var calls int32
+		var secondAt time.Time
+		var first time.Time
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				first = time.Now()
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"success": false, "error": "Too many requests"}`))
+				return
+			}
+			secondAt = time.Now()
+			w.Write([]byte(`{"success": true}`))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithRateLimit(RateLimit{RequestsPerSecond: 1000}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.DeleteTemplate(templateID); !errors.Is(err, ErrRateLimited) {
+			t.Fatal(errors.New("The first request should have been rate limited"))
+		}
+		// Another request of the same CSDK waits for the end of the pause
+		if _, err := c.DeleteTemplate(templateID); err != nil {
+			t.Fatal(err)
+		}
+		if secondAt.Sub(first) < 900*time.Millisecond {
+			t.Error(errors.New("The requests have not been paused"))
+		}
+	})
+
+	t.Run("Should reject invalid limits", func(t *testing.T) {
+		if _, err := New(WithRateLimit(RateLimit{RequestsPerSecond: -1})); err == nil {
+			t.Error(errors.New("A negative rate should have been rejected"))
+		}
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.SetRateLimit(RateLimit{MaxInFlight: -1}); err == nil {
+			t.Error(errors.New("A negative MaxInFlight should have been rejected"))
+		}
+		if err := c.SetRateLimit(RateLimit{}); err != nil || c.limiter != nil {
+			t.Error(errors.New("The zero value should disable the limits"))
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package carbone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RenderOptions are the options of the Carbone render body, see https://carbone.io/api-reference.html#rendering-a-report
+type RenderOptions struct {
+	// ConvertTo is the format of the report, for instance "pdf". The format of the template is kept if empty.
+	ConvertTo string `json:"convertTo,omitempty"`
+	// FormatOptions are the options of the converter, for instance the PDF options. They are sent with the object form of "convertTo".
+	FormatOptions map[string]interface{} `json:"-"`
+	// SkipFormatCheck sends ConvertTo without checking it is a known format, for instance a format of a newer Carbone
+	// version. It is not sent to Carbone Render.
+	SkipFormatCheck bool `json:"-"`
+	// Complement is an object injected into the template with the tag {c.}
+	Complement interface{} `json:"complement,omitempty"`
+	// Enum lists the enumerations used by the convEnum formatter
+	Enum map[string]interface{} `json:"enum,omitempty"`
+	// Translations of the template by locale, used by the tag {t()}
+	Translations map[string]map[string]string `json:"translations,omitempty"`
+	// Lang is the locale of the report, for instance "fr-fr"
+	Lang string `json:"lang,omitempty"`
+	// Timezone is the IANA timezone used to print dates, for instance "Europe/Paris"
+	Timezone string `json:"timezone,omitempty"`
+	// CurrencySource is the ISO 4217 currency of the data, for instance "EUR"
+	CurrencySource string `json:"currencySource,omitempty"`
+	// CurrencyTarget is the ISO 4217 currency of the report
+	CurrencyTarget string `json:"currencyTarget,omitempty"`
+	// CurrencyRates are the exchange rates by currency
+	CurrencyRates map[string]float64 `json:"currencyRates,omitempty"`
+	// VariableStr declares template variables, for instance "{#def = d.id}"
+	VariableStr string `json:"variableStr,omitempty"`
+	// ReportName is the name of the report, it can contain tags like "{d.date}"
+	ReportName string `json:"reportName,omitempty"`
+	// HardRefresh recomputes the table of contents and the page numbers
+	HardRefresh bool `json:"hardRefresh,omitempty"`
+	// BatchSplitBy is the path of the array of data generating one report per element, for instance "d.invoices"
+	BatchSplitBy string `json:"batchSplitBy,omitempty"`
+	// BatchOutput is the format of the batch result, only "zip" is supported
+	BatchOutput string `json:"batchOutput,omitempty"`
+	// BatchReportName is the name of each report of the batch, it can contain tags like "{d.name}.pdf"
+	BatchReportName string `json:"batchReportName,omitempty"`
+}
+
+// RenderRequest is the body of a render request, it is marshalled by the SDK.
+type RenderRequest struct {
+	// Data is injected into the template, it can be any value marshalled by encoding/json.
+	Data interface{} `json:"data"`
+	RenderOptions
+}
+
+// convertToObject is the object form of "convertTo".
+type convertToObject struct {
+	FormatName    string                 `json:"formatName"`
+	FormatOptions map[string]interface{} `json:"formatOptions,omitempty"`
+}
+
+// MarshalJSON marshals the request, "convertTo" is sent as an object if FormatOptions is set.
+func (r RenderRequest) MarshalJSON() ([]byte, error) {
+	// plain has the same fields without the MarshalJSON method
+	type plain RenderRequest
+	body := struct {
+		plain
+		ConvertTo interface{} `json:"convertTo,omitempty"`
+	}{plain: plain(r)}
+	if len(r.FormatOptions) > 0 {
+		body.ConvertTo = convertToObject{FormatName: r.ConvertTo, FormatOptions: r.FormatOptions}
+	} else if r.ConvertTo != "" {
+		body.ConvertTo = r.ConvertTo
+	}
+	return json.Marshal(body)
+}
+
+// UnmarshalJSON parses a render body, "convertTo" can be a string or an object.
+func (r *RenderRequest) UnmarshalJSON(data []byte) error {
+	type plain RenderRequest
+	body := struct {
+		*plain
+		ConvertTo json.RawMessage `json:"convertTo,omitempty"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	r.ConvertTo, r.FormatOptions = "", nil
+	if len(body.ConvertTo) == 0 || string(body.ConvertTo) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(body.ConvertTo, &r.ConvertTo); err == nil {
+		return nil
+	}
+	var obj convertToObject
+	if err := json.Unmarshal(body.ConvertTo, &obj); err != nil {
+		return err
+	}
+	r.ConvertTo, r.FormatOptions = obj.FormatName, obj.FormatOptions
+	return nil
+}
+
+// knownFormats lists the output formats of Carbone Render, RenderOptions.SkipFormatCheck allows the other formats.
+var knownFormats = map[string]bool{
+	"pdf": true, "docx": true, "docm": true, "dotx": true, "doc": true, "odt": true, "ott": true, "fodt": true, "rtf": true,
+	"txt": true, "html": true, "xhtml": true, "md": true, "epub": true,
+	"xlsx": true, "xlsm": true, "xltx": true, "xls": true, "ods": true, "ots": true, "fods": true, "csv": true,
+	"pptx": true, "pptm": true, "potx": true, "ppt": true, "odp": true, "otp": true, "fodp": true,
+	"odg": true, "svg": true, "png": true, "jpg": true, "jpeg": true, "gif": true, "bmp": true, "tiff": true, "webp": true,
+	"xml": true, "idml": true, "js": true, "json": true,
+}
+
+var (
+	langPattern     = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+	formatPattern   = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	timezonePattern = regexp.MustCompile(`^[A-Za-z_]+(/[A-Za-z0-9_+\-]+)*$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	dataPathPattern = regexp.MustCompile(`^d($|[.\[])`)
+)
+
+// Validate checks the request before it is sent to Carbone Render. It returns an error wrapping ErrMissingArgument
+// or ErrInvalidArgument.
+func (r RenderRequest) Validate() error {
+	if r.Data == nil {
+		return fmt.Errorf("Carbone SDK RenderRequest error: %w: data", ErrMissingArgument)
+	}
+	return r.RenderOptions.Validate()
+}
+
+// Validate checks the options before they are sent to Carbone Render. It returns an error wrapping ErrInvalidArgument.
+func (o RenderOptions) Validate() error {
+	invalid := func(field string, format string, args ...interface{}) error {
+		return fmt.Errorf("Carbone SDK RenderRequest error: %w: %s: %s", ErrInvalidArgument, field, fmt.Sprintf(format, args...))
+	}
+	if o.ConvertTo != "" && !formatPattern.MatchString(o.ConvertTo) {
+		return invalid("convertTo", "%q is not a format name like \"pdf\"", o.ConvertTo)
+	}
+	if o.ConvertTo != "" && !o.SkipFormatCheck && !knownFormats[strings.ToLower(o.ConvertTo)] {
+		return invalid("convertTo", "unknown format %q, set SkipFormatCheck to send it anyway", o.ConvertTo)
+	}
+	if o.ConvertTo == "" && len(o.FormatOptions) > 0 {
+		return invalid("convertTo", "formatOptions require a format name")
+	}
+	if o.Lang != "" && !langPattern.MatchString(o.Lang) {
+		return invalid("lang", "%q is not a locale like \"en-us\"", o.Lang)
+	}
+	if o.Timezone != "" && !isTimezone(o.Timezone) {
+		return invalid("timezone", "%q is not an IANA timezone like \"Europe/Paris\"", o.Timezone)
+	}
+	if o.CurrencySource != "" && !currencyPattern.MatchString(o.CurrencySource) {
+		return invalid("currencySource", "%q is not an ISO 4217 currency code like \"EUR\"", o.CurrencySource)
+	}
+	if o.CurrencyTarget != "" && !currencyPattern.MatchString(o.CurrencyTarget) {
+		return invalid("currencyTarget", "%q is not an ISO 4217 currency code like \"EUR\"", o.CurrencyTarget)
+	}
+	for currency, rate := range o.CurrencyRates {
+		if !currencyPattern.MatchString(currency) {
+			return invalid("currencyRates", "%q is not an ISO 4217 currency code like \"EUR\"", currency)
+		}
+		if rate <= 0 {
+			return invalid("currencyRates", "the rate of %s must be greater than 0", currency)
+		}
+	}
+	if o.BatchOutput != "" && o.BatchOutput != "zip" {
+		return invalid("batchOutput", "unknown output %q, only \"zip\" is supported", o.BatchOutput)
+	}
+	if o.BatchOutput != "" && o.BatchSplitBy == "" {
+		return invalid("batchSplitBy", "batchOutput requires batchSplitBy")
+	}
+	if o.BatchReportName != "" && o.BatchSplitBy == "" {
+		return invalid("batchSplitBy", "batchReportName requires batchSplitBy")
+	}
+	if o.BatchSplitBy != "" && !dataPathPattern.MatchString(o.BatchSplitBy) {
+		return invalid("batchSplitBy", "%q must be a path of the data like \"d.list\"", o.BatchSplitBy)
+	}
+	return nil
+}
+
+// isTimezone reports whether name is in the IANA timezone database of the system. If the system has no database,
+// only the syntax of name is checked: the program can embed one by importing time/tzdata.
+func isTimezone(name string) bool {
+	if name == "Local" || !timezonePattern.MatchString(name) {
+		return false
+	}
+	if !hasTimezoneDatabase() {
+		return true
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+var (
+	timezoneDatabaseOnce sync.Once
+	timezoneDatabase     bool
+)
+
+// hasTimezoneDatabase reports whether the IANA timezone database can be loaded.
+func hasTimezoneDatabase() bool {
+	timezoneDatabaseOnce.Do(func() {
+		_, err := time.LoadLocation("Europe/Paris")
+		timezoneDatabase = err == nil
+	})
+	return timezoneDatabase
+}
+
+// marshalRenderRequest validates and marshals a render request.
+func marshalRenderRequest(req RenderRequest) (string, error) {
+	if err := req.Validate(); err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", &MarshalError{Err: err}
+	}
+	return string(body), nil
+}
+
+// RenderReportWithRequest render a report from a templateID, like RenderReport, the request is validated and marshalled by the SDK.
+func (csdk *CSDK) RenderReportWithRequest(ctx context.Context, templateID string, req RenderRequest) (APIResponse, error) {
+	jsonData, err := marshalRenderRequest(req)
+	if err != nil {
+		return APIResponse{}, err
+	}
+	return csdk.RenderReportContext(ctx, templateID, jsonData)
+}
+
+// RenderWithRequest render a report from a templateID OR a template path, like Render,
+// the request is validated and marshalled by the SDK.
+func (csdk *CSDK) RenderWithRequest(ctx context.Context, pathOrTemplateID string, req RenderRequest, args ...string) ([]byte, error) {
+	jsonData, err := marshalRenderRequest(req)
+	if err != nil {
+		return []byte{}, err
+	}
+	return csdk.RenderContext(ctx, pathOrTemplateID, jsonData, args...)
+}
+
+// RenderData render a report from a templateID OR a template path, like Render, with data of any type.
+// data is marshalled by encoding/json, types implementing json.Marshaler are supported. If the data can not be
+// marshalled, a *MarshalError is returned before any request is sent.
+func RenderData[T any](ctx context.Context, csdk *CSDK, pathOrTemplateID string, data T, opts RenderOptions, args ...string) ([]byte, error) {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return []byte{}, &MarshalError{Err: err}
+	}
+	if string(rawData) == "null" {
+		return []byte{}, fmt.Errorf("Carbone SDK RenderData error: %w: data", ErrMissingArgument)
+	}
+	return csdk.RenderWithRequest(ctx, pathOrTemplateID, RenderRequest{Data: json.RawMessage(rawData), RenderOptions: opts}, args...)
+}
@@ -0,0 +1,241 @@
+package carbone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestRenderRequest(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+
+	t.Run("Should marshal the documented render body", func(t *testing.T) {
+		req := RenderRequest{
+			Data: map[string]interface{}{"id": 42},
+			RenderOptions: RenderOptions{
+				ConvertTo:      "pdf",
+				Complement:     map[string]string{"company": "myCompany"},
+				Enum:           map[string]interface{}{"ORDER_STATUS": []string{"open", "close"}},
+				Translations:   map[string]map[string]string{"fr-fr": {"one": "un"}},
+				Lang:           "fr-fr",
+				Timezone:       "Europe/Paris",
+				CurrencySource: "EUR",
+				CurrencyTarget: "USD",
+				CurrencyRates:  map[string]float64{"EUR": 1, "USD": 1.14},
+				VariableStr:    "{#def = d.id}",
+				ReportName:     "{d.id}.pdf",
+				HardRefresh:    true,
+				BatchSplitBy:   "d.list",
+				BatchOutput:    "zip",
+			},
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"data":{"id":42},"complement":{"company":"myCompany"},"enum":{"ORDER_STATUS":["open","close"]},"translations":{"fr-fr":{"one":"un"}},"lang":"fr-fr","timezone":"Europe/Paris","currencySource":"EUR","currencyTarget":"USD","currencyRates":{"EUR":1,"USD":1.14},"variableStr":"{#def = d.id}","reportName":"{d.id}.pdf","hardRefresh":true,"batchSplitBy":"d.list","batchOutput":"zip","convertTo":"pdf"}`
+		if string(body) != expected {
+			t.Error(errors.New("The body is not correct: " + string(body)))
+		}
+	})
+
+	t.Run("Should marshal convertTo as an object with format options", func(t *testing.T) {
+		req := RenderRequest{
+			Data: []int{1, 2},
+			RenderOptions: RenderOptions{
+				ConvertTo:     "csv",
+				FormatOptions: map[string]interface{}{"fieldSeparator": ";"},
+			},
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != `{"data":[1,2],"convertTo":{"formatName":"csv","formatOptions":{"fieldSeparator":";"}}}` {
+			t.Error(errors.New("The body is not correct: " + string(body)))
+		}
+		var parsed RenderRequest
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		if parsed.ConvertTo != "csv" || parsed.FormatOptions["fieldSeparator"] != ";" {
+			t.Error(errors.New("The object form of convertTo is not parsed"))
+		}
+		if err := json.Unmarshal([]byte(`{"data":{},"convertTo":"pdf"}`), &parsed); err != nil || parsed.ConvertTo != "pdf" || parsed.FormatOptions != nil {
+			t.Error(errors.New("The string form of convertTo is not parsed"))
+		}
+	})
+
+	t.Run("Should reject invalid options before sending the request", func(t *testing.T) {
+		invalids := map[string]RenderOptions{
+			"convertTo typo":          {ConvertTo: "pfd"},
+			"convertTo extension":     {ConvertTo: ".pdf", SkipFormatCheck: true},
+			"formatOptions only":      {FormatOptions: map[string]interface{}{"a": 1}},
+			"lang":                    {Lang: "french"},
+			"timezone":                {Timezone: "Europe Paris"},
+			"timezone typo":           {Timezone: "Europe/Pari"},
+			"timezone city":           {Timezone: "Paris"},
+			"timezone local":          {Timezone: "Local"},
+			"currencySource":          {CurrencySource: "euro"},
+			"currencyTarget":          {CurrencyTarget: "US"},
+			"currencyRates code":      {CurrencyRates: map[string]float64{"eur": 1}},
+			"currencyRates rate":      {CurrencyRates: map[string]float64{"EUR": 0}},
+			"batchOutput":             {BatchSplitBy: "d.list", BatchOutput: "tar"},
+			"batchOutput without key": {BatchOutput: "zip"},
+			"batchSplitBy":            {BatchSplitBy: "list"},
+		}
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		// ----
+		for name, opts := range invalids {
+			_, err := csdk.RenderReportWithRequest(context.Background(), templateID, RenderRequest{Data: map[string]string{}, RenderOptions: opts})
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Error(errors.New("Test failled: " + name + " should have been rejected"))
+			}
+		}
+		if _, err := csdk.RenderWithRequest(context.Background(), templateID, RenderRequest{}); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: the data is missing and the method should have thrown an error"))
+		}
+		if httpmock.GetTotalCallCount() != 0 {
+			t.Fatal(errors.New("HTTPMOCH error - no request should have been sent"))
+		}
+	})
+
+	t.Run("Should accept valid options", func(t *testing.T) {
+		valids := []RenderOptions{
+			{ConvertTo: "PDF", Lang: "en", Timezone: "UTC"},
+			{Lang: "zh_Hant_TW", Timezone: "America/Argentina/Buenos_Aires"},
+			{Timezone: "Etc/GMT+2", BatchSplitBy: "d", BatchOutput: "zip"},
+			{BatchSplitBy: "d[0].items"},
+			{ConvertTo: "xlsm", Timezone: "Asia/Kolkata"},
+			{ConvertTo: "docm", Timezone: "GMT"},
+			{ConvertTo: "avif", SkipFormatCheck: true},
+		}
+		for _, opts := range valids {
+			if err := opts.Validate(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("Should render a report from a RenderRequest", func(t *testing.T) {
+		type customer struct {
+			Name string `json:"name"`
+		}
+		content := "<xml>File Content</xml>"
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if string(body) != `{"data":{"name":"Felix"},"lang":"fr-fr","convertTo":"pdf"}` {
+				return httpmock.NewStringResponse(400, `{"success": false, "error": "unexpected body"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`), nil
+		})
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewBytesResponder(200, []byte(content)))
+		// ----
+		req := RenderRequest{Data: customer{Name: "Felix"}, RenderOptions: RenderOptions{ConvertTo: "pdf", Lang: "fr-fr"}}
+		report, err := csdk.RenderWithRequest(context.Background(), templateID, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(report) != content {
+			t.Error(errors.New("The content is not equal"))
+		}
+	})
+
+	t.Run("Should return an error if the data can not be marshalled", func(t *testing.T) {
+		_, err := csdk.RenderReportWithRequest(context.Background(), templateID, RenderRequest{Data: map[string]interface{}{"ch": make(chan int)}})
+		var marshalErr *MarshalError
+		if !errors.As(err, &marshalErr) {
+			t.Error(errors.New("Test failled: the data can not be marshalled and the method should have thrown a MarshalError"))
+		}
+	})
+}
+
+// amount is marshalled by its own MarshalJSON method
+type amount int64
+
+func (a amount) MarshalJSON() ([]byte, error) {
+	if a < 0 {
+		return nil, errors.New("negative amount")
+	}
+	return []byte(`"` + strings.Repeat("9", int(a)) + `"`), nil
+}
+
+type invoice struct {
+	ID    int    `json:"id"`
+	Total amount `json:"total"`
+}
+
+func TestRenderData(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	content := "<xml>File Content</xml>"
+
+	t.Run("Should marshal a struct with a custom json.Marshaler and render it", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if string(body) != `{"data":{"id":42,"total":"999"},"convertTo":"pdf"}` {
+				return httpmock.NewStringResponse(400, `{"success": false, "error": "unexpected body"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`), nil
+		})
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewBytesResponder(200, []byte(content)))
+		// ----
+		report, err := RenderData(context.Background(), csdk, templateID, invoice{ID: 42, Total: 3}, RenderOptions{ConvertTo: "pdf"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(report) != content {
+			t.Error(errors.New("The content is not equal"))
+		}
+	})
+
+	t.Run("Should return a MarshalError before any request", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		// ----
+		_, err := RenderData(context.Background(), csdk, templateID, []invoice{{ID: 1, Total: -1}}, RenderOptions{ConvertTo: "pdf"})
+		var marshalErr *MarshalError
+		if !errors.As(err, &marshalErr) {
+			t.Fatal(errors.New("The error should be a MarshalError"))
+		}
+		if !strings.Contains(err.Error(), "negative amount") {
+			t.Error(errors.New("The error of the json.Marshaler is missing: " + err.Error()))
+		}
+		if httpmock.GetTotalCallCount() != 0 {
+			t.Fatal(errors.New("HTTPMOCH error - no request should have been sent"))
+		}
+	})
+
+	t.Run("Should reject nil data and invalid options before any request", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		// ----
+		var nilInvoice *invoice
+		if _, err := RenderData(context.Background(), csdk, templateID, nilInvoice, RenderOptions{}); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: the data is nil and the method should have thrown an error"))
+		}
+		if _, err := RenderData(context.Background(), csdk, templateID, invoice{}, RenderOptions{ConvertTo: "pfd"}); !errors.Is(err, ErrInvalidArgument) {
+			t.Error(errors.New("Test failled: the format is invalid and the method should have thrown an error"))
+		}
+		if httpmock.GetTotalCallCount() != 0 {
+			t.Fatal(errors.New("HTTPMOCH error - no request should have been sent"))
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package carbone
+
+import (
+	"context"
+	"time"
+)
+
+// RenderResult is a report returned by RenderWithResult with the details of its generation.
+type RenderResult struct {
+	// Report is the content of the report
+	Report []byte
+	// RenderID is the renderId of the report, it is empty if the report has been downloaded directly
+	RenderID string
+	// DirectDownload reports whether the report has been sent in the response of the render, see SetDirectDownload
+	DirectDownload bool
+	// TemplateID is the template used to render the report, the hash of the file for a template path
+	TemplateID string
+	// Reuploaded reports whether the template path has been uploaded during the render,
+	// because it was missing or about to be deleted by Carbone Render
+	Reuploaded bool
+	// ContentType is the media type of the report, for instance "application/pdf"
+	ContentType string
+	// Filename is the name of the report sent in the "Content-Disposition" header, if any
+	Filename string
+	// Timings are the durations of the phases of the render
+	Timings RenderTimings
+}
+
+// RenderTimings are the durations of the phases of a render. A phase which did not happen lasts 0.
+type RenderTimings struct {
+	// Hash is the generation of the templateID of a template path
+	Hash time.Duration
+	// Upload is the total duration of the template uploads
+	Upload time.Duration
+	// Render is the total duration of the render requests, they are sent twice if the template is missing
+	Render time.Duration
+	// Download is the download of the report
+	Download time.Duration
+}
+
+// RenderWithResult render a report from a templateID OR a template path, like Render, and returns it with its renderId,
+// the templateID used, its content type, its file name and the durations of each phase.
+func (csdk *CSDK) RenderWithResult(ctx context.Context, pathOrTemplateID string, jsonData string, args ...string) (result *RenderResult, err error) {
+	ctx, op := csdk.startOperation(ctx, "RenderWithResult")
+	defer func() { op.end(err) }()
+	res := &RenderResult{}
+	report, err := csdk.render(ctx, res, true, pathOrTemplateID, jsonData, args...)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res.DirectDownload = report != nil
+	if report == nil {
+		report, err = csdk.openReport(ctx, "RenderWithResult", res.RenderID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res.ContentType = report.ContentType
+	res.Filename = report.Filename
+	res.Report, err = readReport("RenderWithResult", report)
+	if err != nil {
+		return nil, err
+	}
+	res.Timings.Download = time.Since(start)
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestRenderWithResult(t *testing.T) {
+	templatePath := "./tests/template.test.html"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+	templateID, err := csdk.GenerateTemplateID(templatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportResponder := func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewBytesResponse(200, []byte("%PDF-1.4"))
+		resp.Header.Set("Content-Type", "application/pdf")
+		resp.Header.Set("Content-Disposition", `attachment; filename="invoice.pdf"`)
+		return resp, nil
+	}
+
+	t.Run("Should return the report with the details of a render which uploaded the template", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(404, `{"success": false, "error": "Error while rendering template Error: 404 Not Found"}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": true, "data": {"templateId": "uploaded"}}`))
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/uploaded", httpmock.NewStringResponder(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`))
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, reportResponder)
+		// ----
+		result, err := csdk.RenderWithResult(context.Background(), templatePath, jsonData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result.Report) != "%PDF-1.4" || result.RenderID != renderID || result.TemplateID != "uploaded" || !result.Reuploaded {
+			t.Error(errors.New("The result is not correct"), result)
+		}
+		if result.ContentType != "application/pdf" || result.Filename != "invoice.pdf" {
+			t.Error(errors.New("The content type and the file name should have been read from the headers"), result)
+		}
+		if result.Timings.Hash <= 0 || result.Timings.Upload <= 0 || result.Timings.Render <= 0 || result.Timings.Download <= 0 {
+			t.Error(errors.New("Every phase should have been timed"), result.Timings)
+		}
+	})
+
+	t.Run("Should not time the hash and the upload of a templateID", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/known", httpmock.NewStringResponder(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`))
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, reportResponder)
+		// ----
+		result, err := csdk.RenderWithResult(context.Background(), "known", jsonData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.TemplateID != "known" || result.Reuploaded || result.Timings.Hash != 0 || result.Timings.Upload != 0 {
+			t.Error(errors.New("The result is not correct"), result)
+		}
+	})
+
+	t.Run("Should return the error of the render", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/known", httpmock.NewStringResponder(200, `{"success": false, "error": "Invalid JSON data"}`))
+		// ----
+		result, err := csdk.RenderWithResult(context.Background(), "known", jsonData)
+		if result != nil || err == nil || err.Error() != "Invalid JSON data" {
+			t.Error(errors.New("The error of the API should have been returned"), err)
+		}
+	})
+}
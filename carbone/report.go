@@ -0,0 +1,132 @@
+package carbone
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+)
+
+// Report is a generated report streamed from Carbone Render. The caller must close it.
+type Report struct {
+	io.ReadCloser
+	// ContentType is the media type of the report, for instance "application/pdf"
+	ContentType string
+	// ContentLength is the size of the report in bytes, -1 if it is unknown
+	ContentLength int64
+	// Filename is the name of the report sent in the "Content-Disposition" header, if any
+	Filename string
+}
+
+// GetReportStream Request Carbone Render and return a generated report as a stream, without buffering it in memory.
+// The caller must close the returned Report.
+func (csdk *CSDK) GetReportStream(ctx context.Context, renderID string) (report *Report, err error) {
+	ctx, op := csdk.startOperation(ctx, "GetReportStream", attrRenderID.String(renderID))
+	defer func() { op.end(err) }()
+	return csdk.openReport(ctx, "GetReportStream", renderID)
+}
+
+// GetReportTo Request Carbone Render and copy a generated report to w. It returns the number of bytes written.
+func (csdk *CSDK) GetReportTo(ctx context.Context, renderID string, w io.Writer) (n int64, err error) {
+	ctx, op := csdk.startOperation(ctx, "GetReportTo", attrRenderID.String(renderID))
+	defer func() { op.end(err) }()
+	report, err := csdk.openReport(ctx, "GetReportTo", renderID)
+	if err != nil {
+		return 0, err
+	}
+	return copyReport("GetReportTo", report, w)
+}
+
+// RenderStream render a report from a templateID OR a template path, like Render, and return it as a stream.
+// The caller must close the returned Report.
+func (csdk *CSDK) RenderStream(ctx context.Context, pathOrTemplateID string, jsonData string, args ...string) (report *Report, err error) {
+	ctx, op := csdk.startOperation(ctx, "RenderStream")
+	defer func() { op.end(err) }()
+	return csdk.renderReportStream(ctx, "RenderStream", &RenderResult{}, pathOrTemplateID, jsonData, args...)
+}
+
+// RenderTo render a report from a templateID OR a template path, like Render, and copy it to w.
+// It returns the number of bytes written.
+func (csdk *CSDK) RenderTo(ctx context.Context, pathOrTemplateID string, jsonData string, w io.Writer, args ...string) (n int64, err error) {
+	ctx, op := csdk.startOperation(ctx, "RenderTo")
+	defer func() { op.end(err) }()
+	report, err := csdk.renderReportStream(ctx, "RenderTo", &RenderResult{}, pathOrTemplateID, jsonData, args...)
+	if err != nil {
+		return 0, err
+	}
+	return copyReport("RenderTo", report, w)
+}
+
+// openReport sends the download request of a report, op is the name of the method used in error messages.
+func (csdk *CSDK) openReport(ctx context.Context, op string, renderID string) (*Report, error) {
+	if renderID == "" {
+		return nil, fmt.Errorf("Carbone SDK %s error: %w: renderID", op, ErrMissingArgument)
+	}
+	// http request
+	resp, err := csdk.doHTTPRequest(ctx, "GET", csdk.dialect().reportURL(csdk.apiURL, renderID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+	return csdk.newReport(ctx, op, resp)
+}
+
+// newReport returns the Report of a response containing a report.
+func (csdk *CSDK) newReport(ctx context.Context, op string, resp *http.Response) (*Report, error) {
+	if resp.ContentLength == 0 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Carbone SDK %s request error: The response body is empty: Render again and generate a new renderId: %w", op, ErrReportExpired)
+	}
+	report := &Report{
+		ReadCloser:    &reportBody{countingReader: countingReader{ReadCloser: resp.Body}, ctx: ctx, op: op, tel: csdk.telemetry},
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		report.Filename = params["filename"]
+	}
+	return report, nil
+}
+
+// renderReportStream renders a report like render and returns it, downloaded with the render or with a second request.
+func (csdk *CSDK) renderReportStream(ctx context.Context, op string, res *RenderResult, pathOrTemplateID string, jsonData string, args ...string) (*Report, error) {
+	report, err := csdk.render(ctx, res, true, pathOrTemplateID, jsonData, args...)
+	if err != nil || report != nil {
+		return report, err
+	}
+	return csdk.openReport(ctx, op, res.RenderID)
+}
+
+// copyReport copies and closes a report into w.
+func copyReport(op string, report *Report, w io.Writer) (int64, error) {
+	defer report.Close()
+	n, err := io.Copy(w, report)
+	if err != nil {
+		return n, fmt.Errorf("Carbone SDK %s request error: failled to copy the report: %w", op, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("Carbone SDK %s request error: The response body is empty: Render again and generate a new renderId: %w", op, ErrReportExpired)
+	}
+	return n, nil
+}
+
+// reportBody records the size of the report in the metrics once it is closed.
+type reportBody struct {
+	countingReader
+	ctx  context.Context
+	op   string
+	tel  *telemetry
+	once sync.Once
+}
+
+func (b *reportBody) Close() error {
+	err := b.countingReader.Close()
+	b.once.Do(func() {
+		b.tel.recordReportSize(b.ctx, b.op, b.n)
+	})
+	return err
+}
@@ -0,0 +1,146 @@
+package carbone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestReportStream(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+	content := strings.Repeat("%PDF-1.7 Report content ", 10000)
+
+	registerReport := func() {
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`))
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(200, content)
+			resp.ContentLength = int64(len(content))
+			resp.Header.Set("Content-Type", "application/pdf")
+			resp.Header.Set("Content-Disposition", `attachment; filename="invoice-42.pdf"`)
+			return resp, nil
+		})
+	}
+
+	t.Run("Should stream a report with its metadata", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerReport()
+		// ----
+		report, err := csdk.GetReportStream(context.Background(), renderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer report.Close()
+		if report.ContentType != "application/pdf" || report.ContentLength != int64(len(content)) || report.Filename != "invoice-42.pdf" {
+			t.Error(errors.New("The metadata of the report are not correct"))
+		}
+		body, err := ioutil.ReadAll(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != content {
+			t.Error(errors.New("The content is not equal"))
+		}
+	})
+
+	t.Run("Should render and copy a report to a writer", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerReport()
+		// ----
+		var buf bytes.Buffer
+		n, err := csdk.RenderTo(context.Background(), templateID, jsonData, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(content)) || buf.String() != content {
+			t.Error(errors.New("The content is not equal"))
+		}
+		if httpmock.GetTotalCallCount() != 2 {
+			t.Fatal(errors.New("HTTPMOCH error - the number of requests is invalid"))
+		}
+	})
+
+	t.Run("Should render and return a report as a stream", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerReport()
+		// ----
+		report, err := csdk.RenderStream(context.Background(), templateID, jsonData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer report.Close()
+		body, _ := ioutil.ReadAll(report)
+		if string(body) != content || report.Filename != "invoice-42.pdf" {
+			t.Error(errors.New("The report is not correct"))
+		}
+	})
+
+	t.Run("Should deliver the first bytes before the download is finished", func(t *testing.T) {
+		firstChunk := "%PDF-1.7 first chunk"
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte(firstChunk))
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+			w.Write([]byte(" last chunk"))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := c.GetReportStream(context.Background(), renderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer report.Close()
+		buf := make([]byte, len(firstChunk))
+		if _, err := io.ReadFull(report, buf); err != nil || string(buf) != firstChunk {
+			t.Fatal(errors.New("The first chunk has not been streamed"))
+		}
+		close(release)
+		rest, _ := ioutil.ReadAll(report)
+		if string(rest) != " last chunk" {
+			t.Error(errors.New("The last chunk is missing"))
+		}
+	})
+
+	t.Run("Should return ErrReportExpired for an empty report", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewBytesResponder(200, []byte{}))
+		// ----
+		var buf bytes.Buffer
+		if _, err := csdk.GetReportTo(context.Background(), renderID, &buf); !errors.Is(err, ErrReportExpired) {
+			t.Error(errors.New("GetReportTo should return ErrReportExpired"))
+		}
+	})
+
+	t.Run("Should throw an error because the renderID arg is missing", func(t *testing.T) {
+		report, err := csdk.GetReportStream(context.Background(), "")
+		if !errors.Is(err, ErrMissingArgument) || report != nil {
+			t.Error(errors.New("Test failled: the renderID argument is empty and the method should have thrown an error"))
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy configures how failed requests are sent again. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first request. 0 or 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it is doubled after each attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, 0 means no limit.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay which is randomized, between 0 and 1, to spread the retries of concurrent clients.
+	Jitter float64
+	// RetryableStatusCodes lists the HTTP status codes which are retried.
+	RetryableStatusCodes []int
+	// RetryNetworkErrors retries the requests failing before a response is received (connection refused or reset,
+	// timeout...) when it can not duplicate an operation: the request has not been entirely sent, or its method is
+	// idempotent. A render which timed out once sent is not retried.
+	RetryNetworkErrors bool
+	// RetryableError decides whether a request failing before a response is received is sent again, instead of the
+	// RetryNetworkErrors rule. written reports whether the request has been entirely sent to the server.
+	RetryableError func(req *http.Request, err error, written bool) bool
+}
+
+// DefaultRetryPolicy returns a policy sending a request up to 3 times when the connection fails before the request is
+// sent (or at any time for the downloads and the deletions), or when Carbone Render answers 429, 502, 503 or 504.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            500 * time.Millisecond,
+		MaxDelay:             10 * time.Second,
+		Jitter:               0.5,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryNetworkErrors:   true,
+	}
+}
+
+// doWithRetry sends req and sends it again while the retry policy allows it.
+// The request body is rewound with req.GetBody, a request without GetBody is sent only once.
+// stats is updated with the number of attempts and the bytes sent by the last attempt.
+func (csdk *CSDK) doWithRetry(ctx context.Context, req *http.Request, stats *requestStats) (*http.Response, error) {
+	current := csdk.settings()
+	policy, limiter := current.retryPolicy, current.limiter
+	client := csdk.httpClient()
+	for attempt := 1; ; attempt++ {
+		stats.attempts = attempt
+		canRewind := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if req.Body != nil && req.Body != http.NoBody {
+			stats.contentLength = req.ContentLength
+			stats.sent = &countingReader{ReadCloser: req.Body}
+			req.Body = stats.sent
+		}
+		// Wait for the rate limit of the CSDK, the request is not sent if ctx is done
+		release, err := limiter.wait(ctx)
+		if err != nil {
+			if req.Body != nil {
+				// Like http.Client.Do, the body is always closed
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		// The trace tells whether the server may have received the request when it fails
+		var written int32
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			WroteRequest: func(info httptrace.WroteRequestInfo) {
+				if info.Err == nil {
+					atomic.StoreInt32(&written, 1)
+				}
+			},
+		}))
+		resp, err := client.Do(req)
+		if err != nil {
+			release()
+		} else {
+			// The request is in flight until its body is read and closed
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+		}
+		if pause := limiter.observe(resp); pause > 0 {
+			csdk.logger.Log(ctx, LevelInfo, "Carbone rate limit reached, requests paused", "method", req.Method, "path", req.URL.Path, "pause", pause)
+		}
+		if !canRewind || !policy.retryable(ctx, attempt, req, resp, err, atomic.LoadInt32(&written) == 1) {
+			return resp, err
+		}
+		wait := policy.delay(attempt, resp)
+		keyvals := []interface{}{"method", req.Method, "path", req.URL.Path, "attempt", attempt, "wait", wait}
+		if resp != nil {
+			keyvals = append(keyvals, "status", resp.StatusCode)
+			// Drain the body to reuse the connection
+			io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+			resp.Body.Close()
+		} else {
+			keyvals = append(keyvals, "error", err)
+		}
+		csdk.logger.Log(ctx, LevelInfo, "Carbone request retried", keyvals...)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+		next := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+// validate returns an error if a field of the policy is out of range.
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 || p.BaseDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("Carbone SDK WithRetryPolicy error: MaxAttempts, BaseDelay and MaxDelay must be positive")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("Carbone SDK WithRetryPolicy error: Jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// retryable reports whether the attempt number attempt of req, which returned resp or err, must be sent again.
+// written reports whether req has been entirely sent.
+func (p RetryPolicy) retryable(ctx context.Context, attempt int, req *http.Request, resp *http.Response, err error, written bool) bool {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		if p.RetryableError != nil {
+			return p.RetryableError(req, err, written)
+		}
+		return p.RetryNetworkErrors && (!written || isIdempotent(req.Method))
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdempotent reports whether sending a request with the method twice has the same effect as sending it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodPut:
+		return true
+	}
+	return false
+}
+
+// delay returns the time to wait after the attempt number attempt. The "Retry-After" header of resp is honoured.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		if d > math.MaxInt64/2 {
+			// Without MaxDelay, the doubling stops before it overflows
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		d -= time.Duration(p.Jitter * randFloat64() * float64(d))
+	}
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && retryAfter > d {
+			d = retryAfter
+		}
+	}
+	return d
+}
+
+// parseRetryAfter parses a "Retry-After" header, as a number of seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for the duration d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func randFloat64() float64 {
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return jitterRand.Float64()
+}
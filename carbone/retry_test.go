@@ -0,0 +1,271 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server answering failureStatus to the first failures requests, then calls handler.
+// A failureStatus of 0 closes the connection without answering.
+func newFlakyServer(t *testing.T, failures int32, failureStatus int, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n <= failures {
+			if failureStatus == 0 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				conn.Close()
+				return
+			}
+			w.WriteHeader(failureStatus)
+			w.Write([]byte(`{"success": false, "error": "Bad Gateway"}`))
+			return
+		}
+		handler(w, req)
+	}))
+	return srv, &calls
+}
+
+func newRetrySDK(t *testing.T, apiURL string, policy RetryPolicy) *CSDK {
+	c, err := New(WithToken("token"), WithBaseURL(apiURL), WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRetry(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+	policy := RetryPolicy{
+		MaxAttempts:          4,
+		BaseDelay:            time.Millisecond,
+		MaxDelay:             10 * time.Millisecond,
+		Jitter:               0.5,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway},
+		RetryNetworkErrors:   true,
+	}
+	renderHandler := func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		if string(body) != jsonData {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"success": false, "error": "The body has not been rewound"}`))
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": {"renderId": "` + renderID + `"}}`))
+	}
+
+	t.Run("Should render after the server failed twice", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 2, http.StatusBadGateway, renderHandler)
+		defer srv.Close()
+		cresp, err := newRetrySDK(t, srv.URL, policy).RenderReport(templateID, jsonData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cresp.Data.RenderID != renderID {
+			t.Error(errors.New("renderId has not been returned"))
+		}
+		if atomic.LoadInt32(calls) != 3 {
+			t.Error(errors.New("The number of requests is invalid: " + strconv.Itoa(int(atomic.LoadInt32(calls)))))
+		}
+	})
+
+	t.Run("Should retry a download when the connection is reset", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, 0, func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("report"))
+		})
+		defer srv.Close()
+		report, err := newRetrySDK(t, srv.URL, policy).GetReport(renderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(report) != "report" || atomic.LoadInt32(calls) != 2 {
+			t.Error(errors.New("The request should have been sent twice"))
+		}
+	})
+
+	t.Run("Should not retry a render received by the server when the connection is reset", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, 0, renderHandler)
+		defer srv.Close()
+		if _, err := newRetrySDK(t, srv.URL, policy).RenderReport(templateID, jsonData); err == nil {
+			t.Fatal(errors.New("The error of the connection should have been returned"))
+		}
+		if atomic.LoadInt32(calls) != 1 {
+			t.Error(errors.New("The render should have been sent once"), atomic.LoadInt32(calls))
+		}
+		custom := policy
+		custom.RetryableError = func(req *http.Request, err error, written bool) bool {
+			return req.Method == http.MethodPost && written
+		}
+		cresp, err := newRetrySDK(t, srv.URL, custom).RenderReport(templateID, jsonData)
+		if err != nil || !cresp.Success {
+			t.Fatal(errors.New("RetryableError should have retried the render"), err)
+		}
+	})
+
+	t.Run("Should retry a render when the connection is refused", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(renderHandler))
+		srv.Close()
+		logger := &memoryLogger{}
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithRetryPolicy(policy), WithLogger(logger))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.RenderReport(templateID, jsonData); err == nil {
+			t.Fatal(errors.New("The error of the connection should have been returned"))
+		}
+		retried := 0
+		for _, msg := range logger.messages {
+			if msg == "Info: Carbone request retried" {
+				retried++
+			}
+		}
+		if retried != policy.MaxAttempts-1 {
+			t.Error(errors.New("The render should have been sent on each attempt"), logger.messages)
+		}
+	})
+
+	t.Run("Should rewind the multipart body of AddTemplate", func(t *testing.T) {
+		template, err := ioutil.ReadFile("./tests/template.test.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv, calls := newFlakyServer(t, 2, http.StatusBadGateway, func(w http.ResponseWriter, req *http.Request) {
+			file, _, err := req.FormFile("template")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			content, _ := ioutil.ReadAll(file)
+			if string(content) != string(template) || req.FormValue("payload") != "payload" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"success": true, "data": {"templateId": "` + templateID + `"}}`))
+		})
+		defer srv.Close()
+		resp, err := newRetrySDK(t, srv.URL, policy).AddTemplate("./tests/template.test.html", "payload")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data.TemplateID != templateID || atomic.LoadInt32(calls) != 3 {
+			t.Error(errors.New("The template should have been uploaded at the third attempt"))
+		}
+	})
+
+	t.Run("Should return the last error when all attempts failed", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 10, http.StatusBadGateway, renderHandler)
+		defer srv.Close()
+		_, err := newRetrySDK(t, srv.URL, policy).RenderReport(templateID, jsonData)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+			t.Fatal(errors.New("The error should be an APIError with the status 502"))
+		}
+		if atomic.LoadInt32(calls) != 4 {
+			t.Error(errors.New("The request should have been sent MaxAttempts times"))
+		}
+	})
+
+	t.Run("Should not retry a status which is not retryable", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 10, http.StatusInternalServerError, renderHandler)
+		defer srv.Close()
+		_, err := newRetrySDK(t, srv.URL, policy).RenderReport(templateID, jsonData)
+		if err == nil || atomic.LoadInt32(calls) != 1 {
+			t.Error(errors.New("The request should have been sent once"))
+		}
+	})
+
+	t.Run("Should send requests once by default", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, http.StatusBadGateway, renderHandler)
+		defer srv.Close()
+		_, err := newRetrySDK(t, srv.URL, RetryPolicy{}).RenderReport(templateID, jsonData)
+		if err == nil || atomic.LoadInt32(calls) != 1 {
+			t.Error(errors.New("The request should have been sent once"))
+		}
+	})
+
+	t.Run("Should honour the Retry-After header", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			renderHandler(w, req)
+		}))
+		defer srv.Close()
+		start := time.Now()
+		_, err := newRetrySDK(t, srv.URL, policy).RenderReport(templateID, jsonData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) < time.Second {
+			t.Error(errors.New("The Retry-After delay has not been respected"))
+		}
+	})
+
+	t.Run("Should stop waiting when the context is cancelled", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 10, http.StatusBadGateway, renderHandler)
+		defer srv.Close()
+		slowPolicy := policy
+		slowPolicy.BaseDelay = 10 * time.Second
+		slowPolicy.MaxDelay = 0
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := newRetrySDK(t, srv.URL, slowPolicy).RenderReportContext(ctx, templateID, jsonData)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal(errors.New("The error should wrap context.DeadlineExceeded"))
+		}
+		if time.Since(start) > 2*time.Second || atomic.LoadInt32(calls) != 1 {
+			t.Error(errors.New("The retry should have been aborted"))
+		}
+	})
+
+	t.Run("Should compute an exponential delay", func(t *testing.T) {
+		p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+		expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+		for i, d := range expected {
+			if p.delay(i+1, nil) != d {
+				t.Error(errors.New("Invalid delay for the attempt " + strconv.Itoa(i+1)))
+			}
+		}
+		p.Jitter = 1
+		for i := 1; i < 100; i++ {
+			if d := p.delay(3, nil); d < 0 || d > 400*time.Millisecond {
+				t.Fatal(errors.New("The jitter is out of range"))
+			}
+		}
+		unbounded := RetryPolicy{BaseDelay: time.Second}
+		for attempt := 60; attempt < 70; attempt++ {
+			if d := unbounded.delay(attempt, nil); d < unbounded.delay(attempt-1, nil) {
+				t.Fatal(errors.New("The delay overflowed for the attempt " + strconv.Itoa(attempt)))
+			}
+		}
+	})
+
+	t.Run("Should reject an invalid policy", func(t *testing.T) {
+		for _, p := range []RetryPolicy{{MaxAttempts: -1}, {BaseDelay: -time.Second}, {Jitter: 2}} {
+			if _, err := New(WithRetryPolicy(p)); err == nil {
+				t.Error(errors.New("Test failled: the policy should have been rejected"))
+			}
+			if err := csdk.SetRetryPolicy(p); err == nil {
+				t.Error(errors.New("Test failled: the policy should have been rejected by SetRetryPolicy"))
+			}
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans and the metrics of the SDK.
+const instrumentationName = "github.com/carboneio/carbone-sdk-go/carbone"
+
+// OpenTelemetry attributes set by the SDK.
+const (
+	attrOperation  = attribute.Key("carbone.operation")
+	attrTemplateID = attribute.Key("carbone.template_id")
+	attrRenderID   = attribute.Key("carbone.render_id")
+	attrErrorType  = attribute.Key("error.type")
+)
+
+// WithTracerProvider sets the OpenTelemetry tracer provider creating a span per SDK operation.
+// The global provider is used by default, it creates no span unless the application configured one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) error {
+		o.tracerProvider = tp
+		return nil
+	}
+}
+
+// WithMeterProvider sets the OpenTelemetry meter provider recording the metrics of the SDK.
+// The global provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(o *options) error {
+		o.meterProvider = mp
+		return nil
+	}
+}
+
+// WithPropagator sets how the trace context is injected into the requests sent to Carbone Render.
+// The W3C trace context is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) error {
+		o.propagator = p
+		return nil
+	}
+}
+
+// telemetry holds the OpenTelemetry instruments of a CSDK.
+type telemetry struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	operations metric.Int64Counter
+	duration   metric.Float64Histogram
+	reportSize metric.Int64Histogram
+}
+
+// newTelemetry creates the instruments of the SDK, nil providers fallback to the global ones.
+func newTelemetry(tp trace.TracerProvider, mp metric.MeterProvider, p propagation.TextMapPropagator) (*telemetry, error) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	if p == nil {
+		p = propagation.TraceContext{}
+	}
+	meter := mp.Meter(instrumentationName)
+	t := &telemetry{tracer: tp.Tracer(instrumentationName), propagator: p}
+	var err error
+	if t.operations, err = meter.Int64Counter("carbone.operations",
+		metric.WithDescription("Number of SDK operations by operation and error class"),
+		metric.WithUnit("{operation}")); err != nil {
+		return nil, err
+	}
+	if t.duration, err = meter.Float64Histogram("carbone.operation.duration",
+		metric.WithDescription("Duration of the SDK operations, including the render latency"),
+		metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if t.reportSize, err = meter.Int64Histogram("carbone.report.size",
+		metric.WithDescription("Size of the downloaded reports"),
+		metric.WithUnit("By")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// operation is an SDK operation in progress, it is traced by a span and measured when it ends.
+type operation struct {
+	ctx   context.Context
+	name  string
+	span  trace.Span
+	start time.Time
+	tel   *telemetry
+}
+
+// startOperation starts the span of the operation name, the returned context carries it to the requests and the nested operations.
+func (csdk *CSDK) startOperation(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	ctx, span := csdk.telemetry.tracer.Start(ctx, "Carbone "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, attrOperation.String(name))...))
+	return ctx, &operation{ctx: ctx, name: name, span: span, start: time.Now(), tel: csdk.telemetry}
+}
+
+// setAttributes adds attributes to the span of the operation, for instance the IDs returned by Carbone Render.
+func (op *operation) setAttributes(attrs ...attribute.KeyValue) {
+	op.span.SetAttributes(attrs...)
+}
+
+// end ends the span and records the metrics of the operation, err is its result.
+func (op *operation) end(err error) {
+	attrs := []attribute.KeyValue{attrOperation.String(op.name)}
+	if err != nil {
+		errType := errorType(err)
+		attrs = append(attrs, attrErrorType.String(errType))
+		op.span.SetAttributes(attrErrorType.String(errType))
+		op.span.RecordError(err)
+		op.span.SetStatus(codes.Error, err.Error())
+	}
+	set := metric.WithAttributes(attrs...)
+	op.tel.operations.Add(op.ctx, 1, set)
+	op.tel.duration.Record(op.ctx, time.Since(op.start).Seconds(), set)
+	op.span.End()
+}
+
+// recordReportSize records the size of a report downloaded by the operation name.
+func (t *telemetry) recordReportSize(ctx context.Context, name string, size int64) {
+	t.reportSize.Record(ctx, size, metric.WithAttributes(attrOperation.String(name)))
+}
+
+// resultError returns the error of an operation: err, or apiErr if the API answered "success": false.
+func resultError(apiErr *APIError, err error) error {
+	if err != nil {
+		return err
+	}
+	if apiErr != nil {
+		return apiErr
+	}
+	return nil
+}
+
+// errorType returns the class of an error, it is low-cardinality to be used as a metric attribute.
+func errorType(err error) string {
+	var marshalErr *MarshalError
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &marshalErr):
+		return "marshal"
+	case errors.Is(err, ErrMissingArgument):
+		return "missing_argument"
+	case errors.Is(err, ErrInvalidArgument):
+		return "invalid_argument"
+	case errors.Is(err, ErrUnsupported):
+		return "unsupported"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrReportExpired):
+		return "report_expired"
+	case errors.Is(err, ErrTemplateNotFound):
+		return "template_not_found"
+	case errors.As(err, &apiErr):
+		return "api_error"
+	case errors.As(err, &netErr):
+		return "network"
+	}
+	return "other"
+}
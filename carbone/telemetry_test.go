@@ -0,0 +1,210 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTelemetrySDK returns a CSDK recording its spans and its metrics in memory
+func newTelemetrySDK(t *testing.T) (*CSDK, *tracetest.InMemoryExporter, *sdkmetric.ManualReader) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	c, err := New(WithToken("token"), WithTracerProvider(tp), WithMeterProvider(mp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, exporter, reader
+}
+
+// spanAttribute returns the value of the attribute key of a span
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// collectMetric returns the metric name collected by reader
+func collectMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatal(errors.New("The metric " + name + " has not been recorded"))
+	return nil
+}
+
+func TestTelemetry(t *testing.T) {
+	templatePath := "./tests/template.test.html"
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	jsonData := `{"data":{"firstname":"Felix"},"convertTo":"pdf"}`
+	content := "<xml>File Content</xml>"
+
+	t.Run("Should trace Render with its hash, render, upload, re-render and download steps", func(t *testing.T) {
+		c, exporter, reader := newTelemetrySDK(t)
+		templateID, err := c.GenerateTemplateID(templatePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renders := 0
+		var traceparents []string
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, func(req *http.Request) (*http.Response, error) {
+			traceparents = append(traceparents, req.Header.Get("traceparent"))
+			renders++
+			if renders == 1 {
+				return httpmock.NewStringResponse(200, `{"success": false, "error": "Error while rendering template Error: 404 Not Found"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "`+renderID+`"}}`), nil
+		})
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", func(req *http.Request) (*http.Response, error) {
+			traceparents = append(traceparents, req.Header.Get("traceparent"))
+			return httpmock.NewStringResponse(200, `{"success": true, "data": {"templateId": "`+templateID+`"}}`), nil
+		})
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewStringResponder(200, content))
+		// ----
+		if _, err := c.Render(templatePath, jsonData); err != nil {
+			t.Fatal(err)
+		}
+		spans := exporter.GetSpans()
+		names := []string{}
+		for _, span := range spans {
+			names = append(names, span.Name)
+		}
+		expected := []string{"Carbone GenerateTemplateID", "Carbone RenderReport", "Carbone AddTemplate", "Carbone RenderReport", "Carbone GetReport", "Carbone Render"}
+		if len(names) != len(expected) {
+			t.Fatal(errors.New("Unexpected spans"), names)
+		}
+		for i := range expected {
+			if names[i] != expected[i] {
+				t.Fatal(errors.New("Unexpected spans"), names)
+			}
+		}
+		parent := spans[len(spans)-1]
+		for _, span := range spans[:len(spans)-1] {
+			if span.Parent.SpanID() != parent.SpanContext.SpanID() || span.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+				t.Error(errors.New("The span " + span.Name + " is not a child of the Render span"))
+			}
+		}
+		if spans[1].Status.Code != codes.Error || spanAttribute(spans[1], attrErrorType) != "template_not_found" {
+			t.Error(errors.New("The first render should have been traced as an error"))
+		}
+		if parent.Status.Code == codes.Error || spanAttribute(spans[3], attrRenderID) != renderID || spanAttribute(spans[2], attrTemplateID) != templateID {
+			t.Error(errors.New("The IDs or the status of the spans are not correct"))
+		}
+		// W3C trace context of the span of each request
+		if len(traceparents) != 3 {
+			t.Fatal(errors.New("Unexpected requests"))
+		}
+		for i, span := range []tracetest.SpanStub{spans[1], spans[2], spans[3]} {
+			if traceparents[i] != "00-"+span.SpanContext.TraceID().String()+"-"+span.SpanContext.SpanID().String()+"-01" {
+				t.Error(errors.New("The trace context is not propagated: " + traceparents[i]))
+			}
+		}
+		// Metrics
+		sizes, ok := collectMetric(t, reader, "carbone.report.size").(metricdata.Histogram[int64])
+		if !ok || len(sizes.DataPoints) != 1 || sizes.DataPoints[0].Sum != int64(len(content)) {
+			t.Error(errors.New("The report size is not recorded"))
+		}
+		durations, ok := collectMetric(t, reader, "carbone.operation.duration").(metricdata.Histogram[float64])
+		if !ok {
+			t.Fatal(errors.New("The duration is not a histogram"))
+		}
+		renderLatency := false
+		for _, dp := range durations.DataPoints {
+			if op, _ := dp.Attributes.Value(attrOperation); op.AsString() == "Render" && dp.Count == 1 {
+				renderLatency = true
+			}
+		}
+		if !renderLatency {
+			t.Error(errors.New("The render latency is not recorded"))
+		}
+	})
+
+	t.Run("Should count the operations by error class", func(t *testing.T) {
+		c, _, reader := newTelemetrySDK(t)
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("DELETE", "https://api.carbone.io/template/unknown", httpmock.NewStringResponder(401, `{"success": false, "error": "Unauthorized, please provide a valid API key on the 'Authorization' header"}`))
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/expired", httpmock.NewStringResponder(404, `{"success": false, "error": "File not found"}`))
+		// ----
+		c.DeleteTemplate("unknown")
+		c.GetReport("expired")
+		c.GetReport("")
+		counts, ok := collectMetric(t, reader, "carbone.operations").(metricdata.Sum[int64])
+		if !ok {
+			t.Fatal(errors.New("The operations are not a counter"))
+		}
+		classes := map[string]int64{}
+		for _, dp := range counts.DataPoints {
+			errType, _ := dp.Attributes.Value(attrErrorType)
+			classes[errType.AsString()] += dp.Value
+		}
+		if classes["unauthorized"] != 1 || classes["report_expired"] != 1 || classes["missing_argument"] != 1 {
+			t.Error(errors.New("The error classes are not counted"), classes)
+		}
+	})
+
+	t.Run("Should propagate the trace context of the caller without tracer provider", func(t *testing.T) {
+		c, err := New(WithToken("token"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tp := sdktrace.NewTracerProvider()
+		ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+		defer span.End()
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("DELETE", "https://api.carbone.io/template/id", func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("traceparent") != "00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01" {
+				return httpmock.NewStringResponse(400, `{"success": false, "error": "traceparent"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true}`), nil
+		})
+		// ----
+		if _, err := c.DeleteTemplateContext(ctx, "id"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Should classify the errors", func(t *testing.T) {
+		classes := map[string]error{
+			"canceled":         context.Canceled,
+			"marshal":          &MarshalError{Err: errors.New("json")},
+			"invalid_argument": ErrInvalidArgument,
+			"rate_limited":     &APIError{StatusCode: http.StatusTooManyRequests},
+			"api_error":        &APIError{StatusCode: http.StatusInternalServerError},
+			"other":            errors.New("other"),
+		}
+		for class, err := range classes {
+			if errorType(err) != class {
+				t.Error(errors.New("The error should have been classified as " + class + ", got " + errorType(err)))
+			}
+		}
+	})
+}
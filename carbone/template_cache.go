@@ -0,0 +1,249 @@
+package carbone
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// templateExpiryMargin is the time before the deletion of a template by Carbone Render when it is uploaded again.
+const templateExpiryMargin = time.Minute
+
+// TemplateCacheEntry remembers the templateID of a template file.
+type TemplateCacheEntry struct {
+	// TemplateID is the hash of the template, see GenerateTemplateID
+	TemplateID string `json:"templateId"`
+	// ModTime and Size identify the version of the file, the entry is invalidated when they change
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	// UploadedAt is the last upload of the template by the SDK, zero if it has not been uploaded by the SDK
+	UploadedAt time.Time `json:"uploadedAt,omitempty"`
+	// ExpiresAt is the deletion date of the template set by the "carbone-template-delete-after" header, zero if unknown
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+}
+
+// TemplateCache stores the templateID of the template files rendered by path, to skip hashing them and
+// uploading them again. The implementations must be safe for concurrent use.
+type TemplateCache interface {
+	Get(key string) (TemplateCacheEntry, bool)
+	Set(key string, entry TemplateCacheEntry)
+	Delete(key string)
+}
+
+// WithTemplateCache sets the cache of the templateIDs, see SetTemplateCache.
+func WithTemplateCache(cache TemplateCache) Option {
+	return func(o *options) error {
+		o.templateCache = cache
+		return nil
+	}
+}
+
+// SetTemplateCache set the cache of the templateIDs used by Render and AddTemplate with a template path.
+// A file is hashed again when its modification time or its size change. If the templates are uploaded with the
+// "carbone-template-delete-after" header, a template is uploaded again before Carbone Render deletes it.
+// nil disables the cache, it is the default.
+func (csdk *CSDK) SetTemplateCache(cache TemplateCache) {
+	csdk.mu.Lock()
+	defer csdk.mu.Unlock()
+	csdk.templateCache = cache
+}
+
+// templateCacheKey returns the key of a template path and a payload.
+func templateCacheKey(path string, payload string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path + "\x00" + payload
+}
+
+// templateID returns the templateID of a template file, read from the cache if the file has not changed.
+// expired reports whether the template has been deleted by Carbone Render, it must be uploaded before the render.
+func (csdk *CSDK) templateID(path string, payload string, info os.FileInfo) (templateID string, expired bool, err error) {
+	cache := csdk.settings().templateCache
+	if cache == nil {
+		templateID, err = csdk.GenerateTemplateID(path, payload)
+		return templateID, false, err
+	}
+	key := templateCacheKey(path, payload)
+	entry, ok := cache.Get(key)
+	if ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+		expired = !entry.ExpiresAt.IsZero() && time.Now().Add(templateExpiryMargin).After(entry.ExpiresAt)
+		return entry.TemplateID, expired, nil
+	}
+	// The file is new or it has changed
+	templateID, err = csdk.GenerateTemplateID(path, payload)
+	if err != nil {
+		cache.Delete(key)
+		return "", false, err
+	}
+	cache.Set(key, TemplateCacheEntry{TemplateID: templateID, ModTime: info.ModTime(), Size: info.Size()})
+	return templateID, false, nil
+}
+
+// rememberUpload records the upload of a template file in the cache. deleteAfter is the storage duration of the upload,
+// the "carbone-template-delete-after" header is used if 0.
+func (csdk *CSDK) rememberUpload(path string, payload string, info os.FileInfo, templateID string, deleteAfter time.Duration) {
+	cache := csdk.settings().templateCache
+	if cache == nil || templateID == "" {
+		return
+	}
+	now := time.Now()
+	entry := TemplateCacheEntry{TemplateID: templateID, ModTime: info.ModTime(), Size: info.Size(), UploadedAt: now}
+	if deleteAfter > 0 {
+		entry.ExpiresAt = now.Add(deleteAfter)
+	} else if deleteAfter, ok := csdk.templateDeleteAfter(); ok {
+		entry.ExpiresAt = now.Add(deleteAfter)
+	}
+	cache.Set(templateCacheKey(path, payload), entry)
+}
+
+// templateDeleteAfter returns the storage duration of the templates set by the "carbone-template-delete-after" header.
+func (csdk *CSDK) templateDeleteAfter() (time.Duration, bool) {
+	for k, v := range csdk.settings().apiHeaders {
+		if http.CanonicalHeaderKey(k) != "Carbone-Template-Delete-After" {
+			continue
+		}
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	return 0, false
+}
+
+// memoryTemplateCache is the LRU cache returned by NewMemoryTemplateCache.
+type memoryTemplateCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently used first, the values are keys
+	entries  map[string]*list.Element
+	values   map[string]TemplateCacheEntry
+}
+
+// NewMemoryTemplateCache returns an in-memory TemplateCache keeping the capacity most recently used templates.
+// A capacity lower than 1 keeps 1000 templates.
+func NewMemoryTemplateCache(capacity int) TemplateCache {
+	if capacity < 1 {
+		capacity = 1000
+	}
+	return &memoryTemplateCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+		values:   map[string]TemplateCacheEntry{},
+	}
+}
+
+func (c *memoryTemplateCache) Get(key string) (TemplateCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return TemplateCacheEntry{}, false
+	}
+	c.order.MoveToFront(e)
+	return c.values[key], true
+}
+
+func (c *memoryTemplateCache) Set(key string, entry TemplateCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+	} else {
+		c.entries[key] = c.order.PushFront(key)
+	}
+	c.values[key] = entry
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(string))
+		delete(c.values, oldest.Value.(string))
+	}
+}
+
+func (c *memoryTemplateCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		delete(c.values, key)
+	}
+}
+
+// diskTemplateCache is the cache returned by NewDiskTemplateCache.
+type diskTemplateCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]TemplateCacheEntry
+}
+
+// NewDiskTemplateCache returns a TemplateCache stored in the JSON file path, to keep the templateIDs across restarts.
+// The file is created if it does not exist.
+func NewDiskTemplateCache(path string) (TemplateCache, error) {
+	c := &diskTemplateCache{path: path, entries: map[string]TemplateCacheEntry{}}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Carbone SDK NewDiskTemplateCache error: %w", err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &c.entries); err != nil {
+			return nil, fmt.Errorf("Carbone SDK NewDiskTemplateCache error: failled to parse %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *diskTemplateCache) Get(key string) (TemplateCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *diskTemplateCache) Set(key string, entry TemplateCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	c.save()
+}
+
+func (c *diskTemplateCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.save()
+	}
+}
+
+// save writes the entries to a temporary file renamed to the cache file, a failure only costs a new hash.
+func (c *diskTemplateCache) save() {
+	content, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), c.path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
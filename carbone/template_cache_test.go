@@ -0,0 +1,211 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerTemplateResponders mocks Carbone Render which renders only the uploaded templates, the templateID of
+// an upload is the content of the template.
+func registerTemplateResponders() {
+	var mu sync.Mutex
+	uploaded := map[string]bool{}
+	httpmock.RegisterResponder("POST", "https://api.carbone.io/template", func(req *http.Request) (*http.Response, error) {
+		f, _, err := req.FormFile("template")
+		if err != nil {
+			return httpmock.NewStringResponse(http.StatusBadRequest, ""), nil
+		}
+		content, _ := ioutil.ReadAll(f)
+		templateID := string(content)
+		mu.Lock()
+		uploaded[templateID] = true
+		mu.Unlock()
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"templateId": "`+templateID+`"}}`), nil
+	})
+	httpmock.RegisterResponder("POST", `=~^https://api\.carbone\.io/render/`, func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		exists := uploaded[strings.TrimPrefix(req.URL.Path, "/render/")]
+		mu.Unlock()
+		if !exists {
+			return httpmock.NewStringResponse(http.StatusNotFound, `{"success": false, "error": "Error while rendering template Error: 404 Not Found"}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"success": true, "data": {"renderId": "report"}}`), nil
+	})
+	httpmock.RegisterResponder("GET", "https://api.carbone.io/render/report", httpmock.NewStringResponder(200, "report"))
+}
+
+// templateUploads returns the number of uploads received by the responders of registerTemplateResponders.
+func templateUploads() int {
+	return httpmock.GetCallCountInfo()["POST https://api.carbone.io/template"]
+}
+
+// writeTemplate writes a template file and returns its path
+func writeTemplate(t *testing.T, dir string, content string) string {
+	path := filepath.Join(dir, "template.html")
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTemplateCache(t *testing.T) {
+	t.Run("Should remember the templateID and hash the template again when it changes", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerTemplateResponders()
+		// ----
+		cache := NewMemoryTemplateCache(10)
+		c, err := New(WithToken("token"), WithTemplateCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := writeTemplate(t, t.TempDir(), "template-v1")
+		for i := 0; i < 2; i++ {
+			if report, err := c.Render(path, `{"data":{}}`); err != nil || string(report) != "report" {
+				t.Fatal(errors.New("The report is not correct"), err)
+			}
+		}
+		if templateUploads() != 1 {
+			t.Error(errors.New("The template should have been uploaded once"), templateUploads())
+		}
+		entry, ok := cache.Get(templateCacheKey(path, ""))
+		if !ok || entry.TemplateID != "template-v1" || entry.Size != 11 || entry.UploadedAt.IsZero() || !entry.ExpiresAt.IsZero() {
+			t.Fatal(errors.New("The upload should have been recorded"), entry)
+		}
+		// A stale templateID proves the file is not hashed again while it does not change
+		entry.TemplateID = "cached"
+		cache.Set(templateCacheKey(path, ""), entry)
+		if id, _, _ := c.templateID(path, "", mustStat(t, path)); id != "cached" {
+			t.Error(errors.New("The templateID should have been read from the cache"))
+		}
+		writeTemplate(t, filepath.Dir(path), "template-v2")
+		os.Chtimes(path, time.Now(), time.Now().Add(time.Hour))
+		if _, err := c.Render(path, `{"data":{}}`); err != nil {
+			t.Fatal(err)
+		}
+		if entry, _ := cache.Get(templateCacheKey(path, "")); entry.TemplateID != "template-v2" || templateUploads() != 2 {
+			t.Error(errors.New("The changed template should have been uploaded"), entry)
+		}
+	})
+
+	t.Run("Should upload the template again before carbone-template-delete-after elapses", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerTemplateResponders()
+		// ----
+		cache := NewMemoryTemplateCache(10)
+		c, err := New(WithToken("token"), WithTemplateCache(cache), WithHeaders(map[string]string{"Carbone-Template-Delete-After": "30"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := writeTemplate(t, t.TempDir(), "template")
+		if _, err := c.AddTemplate(path); err != nil {
+			t.Fatal(err)
+		}
+		entry, _ := cache.Get(templateCacheKey(path, ""))
+		if entry.ExpiresAt.Sub(entry.UploadedAt) != 30*time.Second {
+			t.Fatal(errors.New("The expiration date should have been computed from the header"), entry)
+		}
+		// The template expires within the margin, it is uploaded before the render
+		if _, err := c.Render(path, `{"data":{}}`); err != nil {
+			t.Fatal(err)
+		}
+		if templateUploads() != 2 {
+			t.Error(errors.New("The template should have been uploaded again"), templateUploads())
+		}
+	})
+
+	t.Run("Should return a RenderError when the template which expires can not be uploaded", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerTemplateResponders()
+		// ----
+		cache := NewMemoryTemplateCache(10)
+		c, err := New(WithToken("token"), WithTemplateCache(cache), WithHeaders(map[string]string{"Carbone-Template-Delete-After": "30"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := writeTemplate(t, t.TempDir(), "template")
+		if _, err := c.AddTemplate(path); err != nil {
+			t.Fatal(err)
+		}
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/template", httpmock.NewStringResponder(200, `{"success": false, "error": "quota exceeded"}`))
+		res := &RenderResult{}
+		_, err = c.render(context.Background(), res, true, path, `{"data":{}}`)
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || renderErr.TemplateID != "template" || renderErr.Reuploaded || !strings.Contains(err.Error(), "quota exceeded") {
+			t.Fatal(errors.New("The failed upload should have been returned as a RenderError"), err)
+		}
+		if res.Reuploaded {
+			t.Error(errors.New("Reuploaded should not be set when the upload failed"))
+		}
+		if httpmock.GetCallCountInfo()[`POST =~^https://api\.carbone\.io/render/`] != 0 {
+			t.Error(errors.New("The report should not have been rendered"))
+		}
+	})
+
+	t.Run("Should evict the least recently used templates", func(t *testing.T) {
+		cache := NewMemoryTemplateCache(2)
+		cache.Set("a", TemplateCacheEntry{TemplateID: "a"})
+		cache.Set("b", TemplateCacheEntry{TemplateID: "b"})
+		cache.Get("a")
+		cache.Set("c", TemplateCacheEntry{TemplateID: "c"})
+		if _, ok := cache.Get("b"); ok {
+			t.Error(errors.New("b should have been evicted"))
+		}
+		if _, ok := cache.Get("a"); !ok {
+			t.Error(errors.New("a should have been kept"))
+		}
+		cache.Delete("a")
+		if _, ok := cache.Get("a"); ok {
+			t.Error(errors.New("a should have been deleted"))
+		}
+	})
+
+	t.Run("Should keep the templateIDs in a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "templates.json")
+		cache, err := NewDiskTemplateCache(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		cache.Set("a", TemplateCacheEntry{TemplateID: "a", ModTime: modTime, Size: 3})
+		cache.Set("b", TemplateCacheEntry{TemplateID: "b"})
+		cache.Delete("b")
+		reopened, err := NewDiskTemplateCache(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry, ok := reopened.Get("a"); !ok || entry.TemplateID != "a" || !entry.ModTime.Equal(modTime) || entry.Size != 3 {
+			t.Error(errors.New("The entry should have been read from the file"), entry)
+		}
+		if _, ok := reopened.Get("b"); ok {
+			t.Error(errors.New("The deleted entry should not have been saved"))
+		}
+		ioutil.WriteFile(path, []byte("{"), 0o644)
+		if _, err := NewDiskTemplateCache(path); err == nil {
+			t.Error(errors.New("A corrupted cache should return an error"))
+		}
+	})
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
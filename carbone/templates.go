@@ -0,0 +1,232 @@
+package carbone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TemplateInfo describes a template stored by Carbone Render.
+type TemplateInfo struct {
+	// ID is the template ID, VersionID the version of the template. They are equal for a template without versioning.
+	ID        string
+	VersionID string
+	// Name is the human name of the template
+	Name string
+	// Extension is the format of the template, for instance "docx"
+	Extension string
+	// Size is the size of the template in bytes
+	Size int64
+	// CreatedAt is the upload date of the template
+	CreatedAt time.Time
+	// ExpiresAt is the deletion date of the template, zero if it is not deleted automatically
+	ExpiresAt time.Time
+	Comment   string
+	Tags      []string
+	Category  string
+}
+
+// templateInfoJSON is a template returned by the API version 5, the dates are Unix timestamps in seconds.
+type templateInfoJSON struct {
+	ID        string   `json:"id"`
+	VersionID string   `json:"versionId"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Size      int64    `json:"size"`
+	CreatedAt int64    `json:"createdAt"`
+	ExpireAt  int64    `json:"expireAt"`
+	Comment   string   `json:"comment"`
+	Tags      []string `json:"tags"`
+	Category  string   `json:"category"`
+}
+
+// templateInfo converts the JSON of a template.
+func (t templateInfoJSON) templateInfo() TemplateInfo {
+	info := TemplateInfo{
+		ID:        t.ID,
+		VersionID: t.VersionID,
+		Name:      t.Name,
+		Extension: t.Type,
+		Size:      t.Size,
+		Comment:   t.Comment,
+		Tags:      t.Tags,
+		Category:  t.Category,
+	}
+	if t.CreatedAt > 0 {
+		info.CreatedAt = time.Unix(t.CreatedAt, 0)
+	}
+	if t.ExpireAt > 0 {
+		info.ExpiresAt = time.Unix(t.ExpireAt, 0)
+	}
+	return info
+}
+
+// TemplateInfo returns the template described by the response of an upload. Only the IDs and the extension are set,
+// use GetTemplateInfo to read the other fields.
+func (r APIResponse) TemplateInfo() TemplateInfo {
+	versionID := r.Data.VersionID
+	if versionID == "" {
+		versionID = r.Data.TemplateID
+	}
+	return TemplateInfo{ID: r.Data.TemplateID, VersionID: versionID, Extension: r.Data.TemplateFileExtension}
+}
+
+// TemplateFilter selects the templates returned by ListTemplates. The zero value lists every template.
+type TemplateFilter struct {
+	// ID and VersionID select a template or one of its versions
+	ID        string
+	VersionID string
+	// Category selects the templates of a category
+	Category string
+	// Search selects the templates whose name, comment or tags contain it
+	Search string
+	// IncludeVersions lists every version of the templates, only the last version is listed by default
+	IncludeVersions bool
+	// PageSize is the number of templates requested at once, the API default is used if 0
+	PageSize int
+}
+
+// query returns the query parameters of the filter.
+func (f TemplateFilter) query() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("id", f.ID)
+	set("versionId", f.VersionID)
+	set("category", f.Category)
+	set("search", f.Search)
+	if f.IncludeVersions {
+		q.Set("includeVersions", "true")
+	}
+	if f.PageSize > 0 {
+		q.Set("limit", strconv.Itoa(f.PageSize))
+	}
+	return q
+}
+
+// TemplateIterator iterates over the templates returned by ListTemplates, the pages are requested on demand.
+//
+//	it := csdk.ListTemplates(ctx, carbone.TemplateFilter{})
+//	for it.Next() {
+//		fmt.Println(it.Template().Name)
+//	}
+//	if err := it.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+type TemplateIterator struct {
+	csdk   *CSDK
+	ctx    context.Context
+	filter TemplateFilter
+
+	page    []TemplateInfo
+	current TemplateInfo
+	cursor  string
+	hasMore bool
+	err     error
+}
+
+// ListTemplates returns an iterator over the templates stored by Carbone Render, selected by filter.
+// It requires the API version 5, see Features.
+func (csdk *CSDK) ListTemplates(ctx context.Context, filter TemplateFilter) *TemplateIterator {
+	it := &TemplateIterator{csdk: csdk, ctx: ctx, filter: filter, hasMore: true}
+	it.err = csdk.dialect().require("ListTemplates", "listing templates", csdk.dialect().features.TemplateMetadata)
+	return it
+}
+
+// Next advances to the next template, it returns false at the end of the list or if a request failed.
+func (it *TemplateIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || !it.hasMore {
+			return false
+		}
+		it.page, it.cursor, it.hasMore, it.err = it.csdk.listTemplates(it.ctx, it.filter, it.cursor)
+		if it.cursor == "" {
+			// The API can not send the next page without a cursor
+			it.hasMore = false
+		}
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Template returns the current template.
+func (it *TemplateIterator) Template() TemplateInfo {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, nil at the end of the list.
+func (it *TemplateIterator) Err() error {
+	return it.err
+}
+
+// GetTemplateInfo returns the description of a template from its template ID or one of its version IDs.
+// It requires the API version 5, see Features. The error matches ErrTemplateNotFound if the template does not exist.
+func (csdk *CSDK) GetTemplateInfo(ctx context.Context, templateID string) (info TemplateInfo, err error) {
+	ctx, op := csdk.startOperation(ctx, "GetTemplateInfo", attrTemplateID.String(templateID))
+	defer func() { op.end(err) }()
+	if templateID == "" {
+		return TemplateInfo{}, fmt.Errorf("Carbone SDK GetTemplateInfo error: %w: templateID", ErrMissingArgument)
+	}
+	if err := csdk.dialect().require("GetTemplateInfo", "template metadata", csdk.dialect().features.TemplateMetadata); err != nil {
+		return TemplateInfo{}, err
+	}
+	// The ID is a template ID or a version ID, the version is looked up first
+	for _, filter := range []TemplateFilter{{VersionID: templateID, IncludeVersions: true, PageSize: 1}, {ID: templateID, PageSize: 1}} {
+		templates, _, _, err := csdk.listTemplates(ctx, filter, "")
+		if err != nil {
+			return TemplateInfo{}, err
+		}
+		if len(templates) > 0 {
+			return templates[0], nil
+		}
+	}
+	return TemplateInfo{}, fmt.Errorf("Carbone SDK GetTemplateInfo error: %w: %s", ErrTemplateNotFound, templateID)
+}
+
+// listTemplates requests a page of templates.
+func (csdk *CSDK) listTemplates(ctx context.Context, filter TemplateFilter, cursor string) (templates []TemplateInfo, next string, hasMore bool, err error) {
+	ctx, op := csdk.startOperation(ctx, "ListTemplates")
+	defer func() { op.end(err) }()
+	q := filter.query()
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	resp, err := csdk.doHTTPRequest(ctx, "GET", csdk.dialect().templatesURL(csdk.apiURL, q), nil, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", false, newAPIError(resp)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("Carbone SDK ListTemplates request error: failled to read the body: %w", err)
+	}
+	page := struct {
+		Success    bool               `json:"success"`
+		Error      string             `json:"error"`
+		Data       []templateInfoJSON `json:"data"`
+		HasMore    bool               `json:"hasMore"`
+		NextCursor string             `json:"nextCursor"`
+	}{}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, "", false, fmt.Errorf("Carbone SDK ListTemplates request error: failled to parse the JSON response from the body: %w", err)
+	}
+	if !page.Success {
+		return nil, "", false, newAPIErrorFromBody(resp, APIResponse{Error: page.Error}, body)
+	}
+	templates = make([]TemplateInfo, 0, len(page.Data))
+	for _, t := range page.Data {
+		templates = append(templates, t.templateInfo())
+	}
+	return templates, page.NextCursor, page.HasMore, nil
+}
@@ -0,0 +1,144 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// storedTemplates are the templates of registerTemplatesResponder, "invoice" has two versions
+var storedTemplates = []templateInfoJSON{
+	{ID: "invoice", VersionID: "invoice-v2", Name: "Invoice", Type: "docx", Size: 1024, CreatedAt: 1700000000, ExpireAt: 1800000000, Comment: "VAT", Tags: []string{"sales"}, Category: "billing"},
+	{ID: "invoice", VersionID: "invoice-v1", Name: "Invoice", Type: "docx", Size: 1000, CreatedAt: 1600000000, Category: "billing"},
+	{ID: "quote", VersionID: "quote", Name: "Quote", Type: "odt", Category: "billing"},
+	{ID: "payslip", VersionID: "payslip", Name: "Payslip", Type: "xlsx", Category: "hr"},
+	{ID: "letter", VersionID: "letter", Name: "Letter", Type: "docx", Category: "hr"},
+}
+
+// registerTemplatesResponder mocks the template listing of Carbone Render, the cursor is the index of the next template.
+func registerTemplatesResponder() {
+	httpmock.RegisterResponder("GET", "https://api.carbone.io/templates", func(req *http.Request) (*http.Response, error) {
+		q := req.URL.Query()
+		var selected []templateInfoJSON
+		for i, tpl := range storedTemplates {
+			if (q.Get("id") != "" && tpl.ID != q.Get("id")) ||
+				(q.Get("versionId") != "" && tpl.VersionID != q.Get("versionId")) ||
+				(q.Get("category") != "" && tpl.Category != q.Get("category")) ||
+				(q.Get("search") != "" && !strings.Contains(tpl.Name, q.Get("search"))) ||
+				(q.Get("includeVersions") != "true" && i == 1) {
+				continue
+			}
+			selected = append(selected, tpl)
+		}
+		start, _ := strconv.Atoi(q.Get("cursor"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		if limit == 0 {
+			limit = 100
+		}
+		end := start + limit
+		if end > len(selected) {
+			end = len(selected)
+		}
+		page := map[string]interface{}{"success": true, "data": selected[start:end], "hasMore": end < len(selected)}
+		if end < len(selected) {
+			page["nextCursor"] = strconv.Itoa(end)
+		}
+		return httpmock.NewJsonResponse(200, page)
+	})
+}
+
+// templatesPages returns the number of pages requested to the responder of registerTemplatesResponder.
+func templatesPages() int {
+	return httpmock.GetCallCountInfo()["GET https://api.carbone.io/templates"]
+}
+
+func TestTemplates(t *testing.T) {
+	// ---- httpmock
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	registerTemplatesResponder()
+	// ----
+	c, err := New(WithToken("token"), WithAPIVersion(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should iterate over the pages of templates", func(t *testing.T) {
+		httpmock.ZeroCallCounters()
+		it := c.ListTemplates(context.Background(), TemplateFilter{IncludeVersions: true, PageSize: 2})
+		var ids []string
+		for it.Next() {
+			ids = append(ids, it.Template().VersionID)
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		if strings.Join(ids, ",") != "invoice-v2,invoice-v1,quote,payslip,letter" || templatesPages() != 3 {
+			t.Error(errors.New("Every template should have been listed"), ids, templatesPages())
+		}
+	})
+
+	t.Run("Should filter the templates", func(t *testing.T) {
+		it := c.ListTemplates(context.Background(), TemplateFilter{Category: "billing"})
+		var ids []string
+		for it.Next() {
+			ids = append(ids, it.Template().VersionID)
+		}
+		if it.Err() != nil || strings.Join(ids, ",") != "invoice-v2,quote" {
+			t.Error(errors.New("The templates of the category should have been listed"), ids, it.Err())
+		}
+		it = c.ListTemplates(context.Background(), TemplateFilter{Search: "Pay"})
+		if !it.Next() || it.Template().ID != "payslip" || it.Next() {
+			t.Error(errors.New("The search should have returned the payslip"))
+		}
+	})
+
+	t.Run("Should return the metadata of a template or of a version", func(t *testing.T) {
+		info, err := c.GetTemplateInfo(context.Background(), "invoice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.VersionID != "invoice-v2" || info.Name != "Invoice" || info.Extension != "docx" || info.Size != 1024 ||
+			!info.CreatedAt.Equal(time.Unix(1700000000, 0)) || !info.ExpiresAt.Equal(time.Unix(1800000000, 0)) ||
+			info.Comment != "VAT" || len(info.Tags) != 1 || info.Tags[0] != "sales" || info.Category != "billing" {
+			t.Error(errors.New("The metadata are not correct"), info)
+		}
+		info, err = c.GetTemplateInfo(context.Background(), "invoice-v1")
+		if err != nil || info.ID != "invoice" || info.Size != 1000 || !info.ExpiresAt.IsZero() {
+			t.Error(errors.New("The metadata of the version are not correct"), info, err)
+		}
+		if _, err := c.GetTemplateInfo(context.Background(), "unknown"); !errors.Is(err, ErrTemplateNotFound) {
+			t.Error(errors.New("The error should match ErrTemplateNotFound"), err)
+		}
+	})
+
+	t.Run("Should require the API version 5", func(t *testing.T) {
+		it := csdk.ListTemplates(context.Background(), TemplateFilter{})
+		if it.Next() || !errors.Is(it.Err(), ErrUnsupported) {
+			t.Error(errors.New("The listing should not be supported"), it.Err())
+		}
+		if _, err := csdk.GetTemplateInfo(context.Background(), "invoice"); !errors.Is(err, ErrUnsupported) {
+			t.Error(errors.New("The metadata should not be supported"), err)
+		}
+	})
+
+	t.Run("Should describe the template of an upload", func(t *testing.T) {
+		v4 := APIResponse{Success: true, Data: APIResponseData{TemplateID: "tpl", TemplateFileExtension: "docx"}}
+		if info := v4.TemplateInfo(); info.ID != "tpl" || info.VersionID != "tpl" || info.Extension != "docx" {
+			t.Error(errors.New("The template of the version 4 is not correct"), info)
+		}
+		v5 := APIResponse{}
+		if err := dialectOf("5").decodeResponse([]byte(`{"success": true, "data": {"id": "tpl", "versionId": "ver", "type": "odt"}}`), &v5); err != nil {
+			t.Fatal(err)
+		}
+		if info := v5.TemplateInfo(); info.ID != "tpl" || info.VersionID != "ver" || info.Extension != "odt" {
+			t.Error(errors.New("The template of the version 5 is not correct"), info)
+		}
+	})
+}
@@ -0,0 +1,300 @@
+package carbone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AddTemplateOptions configures a template upload.
+type AddTemplateOptions struct {
+	// Payload is an optional payload used to create a different templateID, see GenerateTemplateID.
+	Payload string
+	// Versioning keeps the template ID of the previous uploads and returns a new VersionID, it requires the API version 5.
+	Versioning bool
+	// TemplateID adds the upload as a new version of an existing template, it requires the API version 5 and implies Versioning.
+	TemplateID string
+	// Name, Comment, Tags and Category describe the template, they require the API version 5.
+	Name     string
+	Comment  string
+	Tags     []string
+	Category string
+	// DeleteAfter is the storage duration of the template, it overrides the "carbone-template-delete-after" header
+	// set by SetAPIHeaders for this upload. The default storage duration is used if 0.
+	DeleteAfter time.Duration
+	// Reopen returns a new reader of the template. It is called when the upload is sent again by the retry policy,
+	// or to read the template if no reader is passed. A reader implementing io.Seeker is rewound without Reopen.
+	Reopen func() (io.Reader, error)
+}
+
+// AddTemplateFromReader upload a template read from r to Carbone Render, for instance a template generated in memory
+// or downloaded from an object storage. name is the filename of the template, its extension is used by Carbone.
+// The template is streamed to the API without being buffered in memory.
+func (csdk *CSDK) AddTemplateFromReader(ctx context.Context, name string, r io.Reader, opts AddTemplateOptions) (APIResponse, error) {
+	if name == "" {
+		return APIResponse{}, fmt.Errorf("Carbone SDK AddTemplateFromReader error: %w: name", ErrMissingArgument)
+	}
+	if r == nil && opts.Reopen == nil {
+		return APIResponse{}, fmt.Errorf("Carbone SDK AddTemplateFromReader error: %w: r", ErrMissingArgument)
+	}
+	return csdk.addTemplate(ctx, "AddTemplateFromReader", name, r, opts)
+}
+
+// AddTemplateWithOptions upload a template file to Carbone Render with its metadata, and returns the description
+// of the uploaded template. Unlike AddTemplate, a response "success": false is returned as an *APIError.
+func (csdk *CSDK) AddTemplateWithOptions(ctx context.Context, templateFileName string, opts AddTemplateOptions) (TemplateInfo, error) {
+	_, info, apiErr, err := csdk.addTemplateFile(ctx, "AddTemplateWithOptions", templateFileName, opts)
+	if err = resultError(apiErr, err); err != nil {
+		return TemplateInfo{}, err
+	}
+	return info, nil
+}
+
+// addTemplateFile uploads a template file and records the upload in the template cache.
+func (csdk *CSDK) addTemplateFile(ctx context.Context, op string, templateFileName string, opts AddTemplateOptions) (APIResponse, TemplateInfo, *APIError, error) {
+	if templateFileName == "" {
+		return APIResponse{}, TemplateInfo{}, nil, fmt.Errorf("Carbone SDK %s error: %w: templateFileName", op, ErrMissingArgument)
+	}
+	// Open Template
+	fd, err := os.Open(templateFileName)
+	if err != nil {
+		return APIResponse{}, TemplateInfo{}, nil, err
+	}
+	defer fd.Close()
+	// The file is streamed, it is rewound if the upload is retried
+	cResp, info, apiErr, err := csdk.uploadTemplate(ctx, op, templateFileName, fd, opts)
+	if err == nil && apiErr == nil {
+		if stat, e := fd.Stat(); e == nil {
+			csdk.rememberUpload(templateFileName, opts.Payload, stat, cResp.Data.TemplateID, opts.DeleteAfter)
+		}
+	}
+	return cResp, info, apiErr, err
+}
+
+// addTemplate streams the multipart form of a template upload.
+func (csdk *CSDK) addTemplate(ctx context.Context, op string, name string, r io.Reader, opts AddTemplateOptions) (APIResponse, error) {
+	cResp, _, _, err := csdk.uploadTemplate(ctx, op, name, r, opts)
+	return cResp, err
+}
+
+// uploadTemplate streams the multipart form of a template upload and returns the description of the template.
+func (csdk *CSDK) uploadTemplate(ctx context.Context, op string, name string, r io.Reader, opts AddTemplateOptions) (APIResponse, TemplateInfo, *APIError, error) {
+	ctx, span := csdk.startOperation(ctx, op)
+	fields, err := csdk.dialect().uploadFields(op, opts)
+	if err == nil && opts.DeleteAfter < 0 {
+		err = fmt.Errorf("Carbone SDK %s error: %w: DeleteAfter is negative", op, ErrInvalidArgument)
+	}
+	if err != nil {
+		span.end(err)
+		return APIResponse{}, TemplateInfo{}, nil, err
+	}
+	open, rewindable, size, err := newTemplateOpener(r, opts.Reopen)
+	if err != nil {
+		err = fmt.Errorf("Carbone SDK %s error: %w", op, err)
+		span.end(err)
+		return APIResponse{}, TemplateInfo{}, nil, err
+	}
+	body, contentType, err := newMultipartBody(name, fields, open, rewindable, size)
+	if err != nil {
+		err = fmt.Errorf("Carbone SDK %s error: %w", op, err)
+		span.end(err)
+		return APIResponse{}, TemplateInfo{}, nil, err
+	}
+	// Create the request
+	headerRequest := map[string]string{
+		"Content-Type": contentType,
+	}
+	if opts.DeleteAfter > 0 {
+		headerRequest["carbone-template-delete-after"] = strconv.Itoa(int(opts.DeleteAfter / time.Second))
+	}
+	cResp, info, apiErr, err := csdk.sendUpload(ctx, op, headerRequest, body, opts)
+	span.setAttributes(attrTemplateID.String(cResp.Data.TemplateID))
+	span.end(resultError(apiErr, err))
+	return cResp, info, apiErr, err
+}
+
+// sendUpload sends the upload request and reads the description of the template in the response.
+func (csdk *CSDK) sendUpload(ctx context.Context, op string, headers map[string]string, body io.Reader, opts AddTemplateOptions) (APIResponse, TemplateInfo, *APIError, error) {
+	resp, err := csdk.doHTTPRequest(ctx, "POST", csdk.dialect().uploadURL(csdk.apiURL), headers, body)
+	if err != nil {
+		return APIResponse{}, TemplateInfo{}, nil, err
+	}
+	cResp, respBody, apiErr, err := csdk.readJSONResponse(op, resp)
+	if err != nil || apiErr != nil {
+		return cResp, TemplateInfo{}, apiErr, err
+	}
+	info := cResp.TemplateInfo()
+	// The API version 5 returns the metadata of the template
+	uploaded := struct {
+		Data templateInfoJSON `json:"data"`
+	}{}
+	if json.Unmarshal(respBody, &uploaded) == nil {
+		full := uploaded.Data.templateInfo()
+		full.ID, full.VersionID = info.ID, info.VersionID
+		if full.Extension == "" {
+			full.Extension = info.Extension
+		}
+		info = full
+	}
+	if info.ExpiresAt.IsZero() && opts.DeleteAfter > 0 {
+		info.ExpiresAt = time.Now().Add(opts.DeleteAfter)
+	}
+	return cResp, info, nil, nil
+}
+
+// templateOpener returns the function opening the template for each attempt of an upload.
+// It returns the template reader and a boolean reporting whether the reader must be closed after the upload.
+type templateOpener func(first bool) (io.Reader, bool, error)
+
+// newTemplateOpener returns the templateOpener of r. The boolean reports whether the template can be read again,
+// size is the number of bytes of the template, -1 if it is unknown.
+func newTemplateOpener(r io.Reader, reopen func() (io.Reader, error)) (open templateOpener, rewindable bool, size int64, err error) {
+	reopenFunc := func(bool) (io.Reader, bool, error) {
+		tr, err := reopen()
+		if err == nil && tr == nil {
+			err = errors.New("Reopen returned a nil reader")
+		}
+		return tr, true, err
+	}
+	if r == nil {
+		return reopenFunc, true, -1, nil
+	}
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return func(first bool) (io.Reader, bool, error) {
+			if first {
+				return r, false, nil
+			}
+			return reopenFunc(first)
+		}, reopen != nil, -1, nil
+	}
+	// Rewind the reader to its current position
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = seeker.Seek(start, io.SeekStart)
+	}
+	if err != nil {
+		return nil, false, 0, err
+	}
+	return func(first bool) (io.Reader, bool, error) {
+		if !first {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, false, err
+			}
+		}
+		return r, false, nil
+	}, true, end - start, nil
+}
+
+// streamBody is a request body written on the fly, getBody generates it again when the request is retried.
+// getBody is nil if the body can be sent only once. size is the length of the body, -1 if it is unknown.
+type streamBody struct {
+	io.ReadCloser
+	getBody func() (io.ReadCloser, error)
+	size    int64
+}
+
+// newMultipartBody returns the multipart form of a template upload and its content type.
+// The form is written to a pipe while the request is sent. If the size of the template is known, the length
+// of the form is computed, otherwise the form is sent chunked.
+func newMultipartBody(name string, fields []formField, open templateOpener, rewindable bool, size int64) (*streamBody, string, error) {
+	// The boundary must be the same for every attempt, the Content-Type header is sent again as it is
+	boundary := multipart.NewWriter(nil).Boundary()
+	length := int64(-1)
+	if size >= 0 {
+		// The form around the template does not depend on its content
+		overhead := &countingWriter{}
+		w := multipart.NewWriter(overhead)
+		w.SetBoundary(boundary)
+		if err := writeTemplateForm(w, name, fields, strings.NewReader("")); err != nil {
+			return nil, "", err
+		}
+		length = overhead.n + size
+	}
+	// The writer of the previous attempt, the transport may close its body after the next attempt started
+	var mu sync.Mutex
+	var previous *io.PipeReader
+	var previousDone chan struct{}
+	generate := func(first bool) (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if previous != nil {
+			// Stop the previous writer before the template is rewound or reopened, it may still be reading it
+			previous.Close()
+			<-previousDone
+		}
+		template, owned, err := open(first)
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		done := make(chan struct{})
+		previous, previousDone = pr, done
+		go func() {
+			defer close(done)
+			w := multipart.NewWriter(pw)
+			w.SetBoundary(boundary)
+			err := writeTemplateForm(w, name, fields, template)
+			if c, ok := template.(io.Closer); owned && ok {
+				c.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+	first, err := generate(true)
+	if err != nil {
+		return nil, "", err
+	}
+	body := &streamBody{ReadCloser: first, size: length}
+	if rewindable {
+		body.getBody = func() (io.ReadCloser, error) {
+			return generate(false)
+		}
+	}
+	return body, "multipart/form-data; boundary=" + boundary, nil
+}
+
+// countingWriter counts the bytes written.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// writeTemplateForm writes the fields of a template upload.
+func writeTemplateForm(w *multipart.Writer, name string, fields []formField, template io.Reader) error {
+	// Create the data object to send
+	// { "payload":"", "template": readstream(file...) }
+	for _, field := range fields {
+		if err := w.WriteField(field.name, field.value); err != nil {
+			return err
+		}
+	}
+	// Create the FormData
+	fw, err := w.CreateFormFile("template", name)
+	if err != nil {
+		return err
+	}
+	// Write file field from the template reader
+	if _, err := io.Copy(fw, template); err != nil {
+		return err
+	}
+	// Important if you do not close the multipart writer you will not have a terminating boundry
+	return w.Close()
+}
@@ -0,0 +1,295 @@
+package carbone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAddTemplateFromReader(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	template := "<!DOCTYPE html><html><body>{d.name}</body></html>"
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatusCodes: []int{http.StatusBadGateway}}
+
+	// checkUpload answers the templateId if the upload contains the template and the payload
+	checkUpload := func(w http.ResponseWriter, req *http.Request) {
+		file, header, err := req.FormFile("template")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		content, _ := ioutil.ReadAll(file)
+		if string(content) != template || header.Filename != "invoice.html" || req.FormValue("payload") != "payload" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": {"templateId": "` + templateID + `"}}`))
+	}
+
+	t.Run("Should upload a template from a reader and rewind it when the upload is retried", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, http.StatusBadGateway, checkUpload)
+		defer srv.Close()
+		c := newRetrySDK(t, srv.URL, policy)
+		resp, err := c.AddTemplateFromReader(context.Background(), "invoice.html", strings.NewReader(template), AddTemplateOptions{Payload: "payload"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data.TemplateID != templateID || atomic.LoadInt32(calls) != 2 {
+			t.Error(errors.New("The template should have been uploaded at the second attempt"))
+		}
+	})
+
+	t.Run("Should not rewind the template while the previous attempt reads it", func(t *testing.T) {
+		// The server answers before reading the body: the previous attempt is still writing the template when
+		// the upload is retried
+		large := bytes.Repeat([]byte("a"), 20<<20)
+		srv, calls := newFlakyServer(t, 2, http.StatusBadGateway, func(w http.ResponseWriter, req *http.Request) {
+			file, _, err := req.FormFile("template")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			content, _ := ioutil.ReadAll(file)
+			if !bytes.Equal(content, large) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"success": true, "data": {"templateId": "` + templateID + `"}}`))
+		})
+		defer srv.Close()
+		c := newRetrySDK(t, srv.URL, policy)
+		resp, err := c.AddTemplateFromReader(context.Background(), "invoice.html", bytes.NewReader(large), AddTemplateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data.TemplateID != templateID || atomic.LoadInt32(calls) != 3 {
+			t.Error(errors.New("The template should have been uploaded at the third attempt"))
+		}
+	})
+
+	t.Run("Should reopen a template which can not be rewound", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 2, http.StatusBadGateway, checkUpload)
+		defer srv.Close()
+		c := newRetrySDK(t, srv.URL, policy)
+		reopened := 0
+		opts := AddTemplateOptions{
+			Payload: "payload",
+			Reopen: func() (io.Reader, error) {
+				reopened++
+				return ioutil.NopCloser(strings.NewReader(template)), nil
+			},
+		}
+		resp, err := c.AddTemplateFromReader(context.Background(), "invoice.html", ioutil.NopCloser(strings.NewReader(template)), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data.TemplateID != templateID || atomic.LoadInt32(calls) != 3 || reopened != 2 {
+			t.Error(errors.New("The template should have been reopened for each retry"))
+		}
+	})
+
+	t.Run("Should read the template with Reopen if no reader is passed", func(t *testing.T) {
+		srv, _ := newFlakyServer(t, 0, http.StatusBadGateway, checkUpload)
+		defer srv.Close()
+		c := newRetrySDK(t, srv.URL, policy)
+		opts := AddTemplateOptions{
+			Payload: "payload",
+			Reopen: func() (io.Reader, error) {
+				return strings.NewReader(template), nil
+			},
+		}
+		resp, err := c.AddTemplateFromReader(context.Background(), "invoice.html", nil, opts)
+		if err != nil || resp.Data.TemplateID != templateID {
+			t.Error(errors.New("The template has not been uploaded"))
+		}
+	})
+
+	t.Run("Should send only once a template which can not be read twice", func(t *testing.T) {
+		srv, calls := newFlakyServer(t, 1, http.StatusBadGateway, checkUpload)
+		defer srv.Close()
+		c := newRetrySDK(t, srv.URL, policy)
+		_, err := c.AddTemplateFromReader(context.Background(), "invoice.html", ioutil.NopCloser(strings.NewReader(template)), AddTemplateOptions{Payload: "payload"})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+			t.Fatal(errors.New("The error of the first attempt should have been returned"))
+		}
+		if atomic.LoadInt32(calls) != 1 {
+			t.Error(errors.New("The upload should have been sent once"))
+		}
+	})
+
+	t.Run("Should stream the template while it is read", func(t *testing.T) {
+		received := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// Read the beginning of the form before the template is completely written
+			buf := make([]byte, 64)
+			io.ReadFull(req.Body, buf)
+			close(received)
+			ioutil.ReadAll(req.Body)
+			w.Write([]byte(`{"success": true, "data": {"templateId": "` + templateID + `"}}`))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write(bytes.Repeat([]byte("a"), 128))
+			select {
+			case <-received:
+				pw.Close()
+			case <-time.After(5 * time.Second):
+				pw.CloseWithError(errors.New("the template has been buffered before being sent"))
+			}
+		}()
+		resp, err := c.AddTemplateFromReader(context.Background(), "invoice.html", pr, AddTemplateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data.TemplateID != templateID {
+			t.Error(errors.New("The template id is different"))
+		}
+	})
+
+	t.Run("Should send the length of the form when the size of the template is known", func(t *testing.T) {
+		var lengths []int64
+		var encodings [][]string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if req.ContentLength >= 0 && int64(len(body)) != req.ContentLength {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			lengths = append(lengths, req.ContentLength)
+			encodings = append(encodings, req.TransferEncoding)
+			w.Write([]byte(`{"success": true, "data": {"templateId": "` + templateID + `"}}`))
+		}))
+		defer srv.Close()
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AddTemplate("./tests/template.test.html", "payload"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AddTemplateFromReader(context.Background(), "invoice.html", strings.NewReader(template), AddTemplateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AddTemplateFromReader(context.Background(), "invoice.html", ioutil.NopCloser(strings.NewReader(template)), AddTemplateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if len(lengths) != 3 || lengths[0] <= 0 || lengths[1] <= int64(len(template)) || len(encodings[0]) != 0 || len(encodings[1]) != 0 {
+			t.Error(errors.New("The files and the seekers should have been sent with their length"), lengths, encodings)
+		}
+		if len(lengths) == 3 && (lengths[2] != -1 || len(encodings[2]) != 1 || encodings[2][0] != "chunked") {
+			t.Error(errors.New("A reader of unknown size should have been sent chunked"), lengths, encodings)
+		}
+	})
+
+	t.Run("Should throw an error because an argument is missing", func(t *testing.T) {
+		_, err := csdk.AddTemplateFromReader(context.Background(), "", strings.NewReader(template), AddTemplateOptions{})
+		if !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: the name argument is empty and the method should have thrown an error"))
+		}
+		_, err = csdk.AddTemplateFromReader(context.Background(), "invoice.html", nil, AddTemplateOptions{})
+		if !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: the reader is nil and the method should have thrown an error"))
+		}
+	})
+}
+
+func TestAddTemplateWithOptions(t *testing.T) {
+	opts := AddTemplateOptions{
+		Name:        "Invoice",
+		Comment:     "VAT",
+		Tags:        []string{"sales", "2024"},
+		Category:    "billing",
+		TemplateID:  "invoice",
+		DeleteAfter: time.Hour,
+	}
+	// The stand-in of Carbone Render answers the metadata received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, _, err := req.FormFile("template"); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.FormValue("versioning") != "true" || req.FormValue("id") != "invoice" || req.Header.Get("carbone-template-delete-after") != "3600" {
+			w.Write([]byte(`{"success": false, "error": "unexpected upload"}`))
+			return
+		}
+		w.Write([]byte(`{"success": true, "data": {"id": "` + req.FormValue("id") + `", "versionId": "invoice-v2", "type": "html", "size": 50,` +
+			` "createdAt": 1700000000, "name": "` + req.FormValue("name") + `", "comment": "` + req.FormValue("comment") + `",` +
+			` "category": "` + req.FormValue("category") + `", "tags": ` + req.FormValue("tags") + `}}`))
+	}))
+	defer srv.Close()
+	c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithAPIVersion(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should upload the metadata of the template and return them", func(t *testing.T) {
+		info, err := c.AddTemplateWithOptions(context.Background(), "./tests/template.test.html", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.ID != "invoice" || info.VersionID != "invoice-v2" || info.Extension != "html" || info.Size != 50 ||
+			info.Name != "Invoice" || info.Comment != "VAT" || info.Category != "billing" ||
+			strings.Join(info.Tags, ",") != "sales,2024" || !info.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+			t.Error(errors.New("The template info is not correct"), info)
+		}
+		if time.Until(info.ExpiresAt) < 59*time.Minute || time.Until(info.ExpiresAt) > time.Hour {
+			t.Error(errors.New("The expiry should be computed from DeleteAfter"), info.ExpiresAt)
+		}
+	})
+
+	t.Run("Should return the error of the API", func(t *testing.T) {
+		_, err := c.AddTemplateWithOptions(context.Background(), "./tests/template.test.html", AddTemplateOptions{Name: "Invoice"})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || err.Error() != "unexpected upload" {
+			t.Error(errors.New("The error of the API should have been returned"), err)
+		}
+	})
+
+	t.Run("Should record the expiry of the upload in the template cache", func(t *testing.T) {
+		cache := NewMemoryTemplateCache(0)
+		c, err := New(WithToken("token"), WithBaseURL(srv.URL), WithAPIVersion(5), WithTemplateCache(cache))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AddTemplateWithOptions(context.Background(), "./tests/template.test.html", opts); err != nil {
+			t.Fatal(err)
+		}
+		entry, ok := cache.Get(templateCacheKey("./tests/template.test.html", ""))
+		if !ok || entry.TemplateID != "invoice" || time.Until(entry.ExpiresAt) < 59*time.Minute {
+			t.Error(errors.New("The upload should have been cached with its expiry"), entry)
+		}
+	})
+
+	t.Run("Should require the API version 5 for the metadata", func(t *testing.T) {
+		for _, o := range []AddTemplateOptions{{Name: "Invoice"}, {Tags: []string{"sales"}}, {TemplateID: "invoice"}} {
+			if _, err := csdk.AddTemplateWithOptions(context.Background(), "./tests/template.test.html", o); !errors.Is(err, ErrUnsupported) {
+				t.Error(errors.New("The metadata should not be supported"), o, err)
+			}
+		}
+	})
+
+	t.Run("Should throw an error because an argument is not valid", func(t *testing.T) {
+		if _, err := c.AddTemplateWithOptions(context.Background(), "", opts); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("The template path is missing"), err)
+		}
+		if _, err := c.AddTemplateWithOptions(context.Background(), "./tests/template.test.html", AddTemplateOptions{DeleteAfter: -time.Second}); !errors.Is(err, ErrInvalidArgument) {
+			t.Error(errors.New("A negative DeleteAfter should have been rejected"), err)
+		}
+	})
+}
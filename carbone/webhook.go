@@ -0,0 +1,179 @@
+package carbone
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// DefaultWebhookSecretHeader is the header read by WebhookHandler to verify the secret of a callback.
+	DefaultWebhookSecretHeader = "X-Carbone-Webhook-Secret"
+	// maxWebhookBodySize is the maximum size of a callback payload.
+	maxWebhookBodySize = 1 << 20
+)
+
+// RenderAsync render a report from a templateID without waiting for it. Carbone Render answers an acknowledgement and
+// sends the renderId to webhookURL once the report is generated, receive it with a WebhookHandler.
+// webhookURL takes precedence over the "carbone-webhook-url" header passed to SetAPIHeaders.
+func (csdk *CSDK) RenderAsync(ctx context.Context, templateID string, req RenderRequest, webhookURL string) (cResp APIResponse, err error) {
+	ctx, op := csdk.startOperation(ctx, "RenderAsync", attrTemplateID.String(templateID))
+	defer func() { op.end(err) }()
+	if templateID == "" {
+		return APIResponse{}, fmt.Errorf("Carbone SDK RenderAsync error: %w: templateID", ErrMissingArgument)
+	}
+	if webhookURL == "" {
+		return APIResponse{}, fmt.Errorf("Carbone SDK RenderAsync error: %w: webhookURL", ErrMissingArgument)
+	}
+	if err := checkWebhookURL("RenderAsync", webhookURL); err != nil {
+		return APIResponse{}, err
+	}
+	jsonData, err := marshalRenderRequest(req)
+	if err != nil {
+		return APIResponse{}, err
+	}
+	headerRequest := map[string]string{
+		"Content-Type":        "application/json",
+		"carbone-webhook-url": webhookURL,
+	}
+	csdk.setIdempotencyKey(headerRequest)
+	cResp, apiErr, err := csdk.doJSONRequest(ctx, "RenderAsync", "POST", csdk.dialect().renderURL(csdk.apiURL, templateID, false), headerRequest, bytes.NewBufferString(jsonData))
+	return cResp, resultError(apiErr, err)
+}
+
+// checkWebhookURL returns an error if webhookURL is not an absolute http or https URL.
+func checkWebhookURL(op string, webhookURL string) error {
+	if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Carbone SDK %s error: %w: webhookURL: %q is not an absolute http or https URL", op, ErrInvalidArgument, webhookURL)
+	}
+	return nil
+}
+
+// WebhookEvent is the callback sent by Carbone Render when an asynchronous render is done.
+type WebhookEvent struct {
+	// Success is false if the render failed, the reason is in Error
+	Success bool
+	// RenderID identifies the report, download it with GetReport
+	RenderID string
+	// Error and Message are the "error" and "message" fields of the callback, if any
+	Error   string
+	Message string
+	// Header contains the headers of the callback request
+	Header http.Header
+
+	csdk *CSDK
+}
+
+// GetReport downloads the report of the event with the CSDK of the WebhookHandler.
+func (e WebhookEvent) GetReport(ctx context.Context) ([]byte, error) {
+	if e.csdk == nil {
+		return []byte{}, fmt.Errorf("Carbone SDK WebhookEvent error: %w: the handler has not been created by NewWebhookHandler", ErrMissingArgument)
+	}
+	return e.csdk.GetReportContext(ctx, e.RenderID)
+}
+
+// GetReportStream downloads the report of the event as a stream, like GetReport. The caller must close the returned Report.
+func (e WebhookEvent) GetReportStream(ctx context.Context) (*Report, error) {
+	if e.csdk == nil {
+		return nil, fmt.Errorf("Carbone SDK WebhookEvent error: %w: the handler has not been created by NewWebhookHandler", ErrMissingArgument)
+	}
+	return e.csdk.GetReportStream(ctx, e.RenderID)
+}
+
+// WebhookHandler is an http.Handler receiving the callbacks of RenderAsync.
+// It answers 401 if the secret is wrong, 400 if the payload is invalid and 500 if OnRender returns an error.
+type WebhookHandler struct {
+	// Secret is compared with the SecretHeader of each callback. Carbone Render does not send the header itself,
+	// it must be added by the reverse proxy or the API gateway relaying the callbacks to the handler.
+	// Callbacks are not verified if Secret is empty.
+	Secret string
+	// SecretHeader is the header carrying the secret, DefaultWebhookSecretHeader if empty.
+	SecretHeader string
+	// Verify is an optional check of the callback, for instance the signature added by a proxy.
+	// The callback is rejected with a 401 if it returns an error.
+	Verify func(r *http.Request, body []byte) error
+	// OnRender is called for each verified callback, failed renders included.
+	OnRender func(ctx context.Context, event WebhookEvent) error
+
+	csdk *CSDK
+}
+
+// NewWebhookHandler returns a WebhookHandler calling onRender for each callback, the events download their report with csdk.
+// secret is optional, see WebhookHandler.Secret.
+func (csdk *CSDK) NewWebhookHandler(secret string, onRender func(ctx context.Context, event WebhookEvent) error) *WebhookHandler {
+	return &WebhookHandler{Secret: secret, OnRender: onRender, csdk: csdk}
+}
+
+// ServeHTTP parses and verifies a callback of Carbone Render, then dispatches it to OnRender.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if !h.verifySecret(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.Verify != nil {
+		if err := h.Verify(r, body); err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	event, err := parseWebhookEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	event.Header = r.Header
+	event.csdk = h.csdk
+	if h.OnRender != nil {
+		if err := h.OnRender(r.Context(), event); err != nil {
+			http.Error(w, "callback error", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"success":true}`))
+}
+
+// verifySecret reports whether the callback carries the secret of the handler.
+func (h *WebhookHandler) verifySecret(r *http.Request) bool {
+	if h.Secret == "" {
+		return true
+	}
+	header := h.SecretHeader
+	if header == "" {
+		header = DefaultWebhookSecretHeader
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(header)), []byte(h.Secret)) == 1
+}
+
+// parseWebhookEvent parses the payload of a callback, it has the same shape as the response of a render:
+// { "success": true, "data": { "renderId": "..." } }
+func parseWebhookEvent(body []byte) (WebhookEvent, error) {
+	cResp := APIResponse{}
+	if err := json.Unmarshal(body, &cResp); err != nil {
+		return WebhookEvent{}, fmt.Errorf("Carbone SDK webhook error: failled to parse the JSON payload: %w", err)
+	}
+	if cResp.Success && cResp.Data.RenderID == "" {
+		return WebhookEvent{}, fmt.Errorf("Carbone SDK webhook error: %w: renderId", ErrMissingArgument)
+	}
+	return WebhookEvent{
+		Success:  cResp.Success,
+		RenderID: cResp.Data.RenderID,
+		Error:    cResp.Error,
+		Message:  cResp.Message,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package carbone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestRenderAsync(t *testing.T) {
+	templateID := "f90e67221d7d5ee11058a000bdb997fb41bf149b1f88b45cb1aba9edcab8f868"
+	webhookURL := "https://example.com/carbone/webhook"
+
+	t.Run("Should send the webhook URL and return the acknowledgement", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			if req.Header.Get("carbone-webhook-url") != webhookURL || string(body) != `{"data":{"id":42},"convertTo":"pdf"}` {
+				return httpmock.NewStringResponse(400, `{"success": false, "error": "unexpected request"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"success": true, "message": "A render ID will be sent to your callback URL when the document is generated"}`), nil
+		})
+		// ----
+		// The webhook of the call takes precedence over the client headers
+		csdk.SetAPIHeaders(map[string]string{"Carbone-Webhook-Url": "https://example.com/other"})
+		defer csdk.SetAPIHeaders(map[string]string{})
+		resp, err := csdk.RenderAsync(context.Background(), templateID, RenderRequest{Data: map[string]int{"id": 42}, RenderOptions: RenderOptions{ConvertTo: "pdf"}}, webhookURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Success || resp.Message == "" {
+			t.Error(errors.New("The acknowledgement is not returned"))
+		}
+	})
+
+	t.Run("Should return the API error if the render is rejected", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("POST", "https://api.carbone.io/render/"+templateID, httpmock.NewStringResponder(200, `{"success": false, "error": "Error while rendering template Error: 404 Not Found"}`))
+		// ----
+		_, err := csdk.RenderAsync(context.Background(), templateID, RenderRequest{Data: map[string]int{}}, webhookURL)
+		if !errors.Is(err, ErrTemplateNotFound) {
+			t.Error(errors.New("Test failled: the template does not exist and the method should have thrown an error"))
+		}
+	})
+
+	t.Run("Should throw an error because an argument is missing or invalid", func(t *testing.T) {
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		// ----
+		if _, err := csdk.RenderAsync(context.Background(), "", RenderRequest{Data: 1}, webhookURL); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: the templateID is empty and the method should have thrown an error"))
+		}
+		if _, err := csdk.RenderAsync(context.Background(), templateID, RenderRequest{Data: 1}, ""); !errors.Is(err, ErrMissingArgument) {
+			t.Error(errors.New("Test failled: the webhookURL is empty and the method should have thrown an error"))
+		}
+		if _, err := csdk.RenderAsync(context.Background(), templateID, RenderRequest{Data: 1}, "/webhook"); !errors.Is(err, ErrInvalidArgument) {
+			t.Error(errors.New("Test failled: the webhookURL is relative and the method should have thrown an error"))
+		}
+		if httpmock.GetTotalCallCount() != 0 {
+			t.Fatal(errors.New("HTTPMOCH error - no request should have been sent"))
+		}
+	})
+}
+
+func TestWebhookHandler(t *testing.T) {
+	renderID := "r3209jf903j2f90j2309fj3209fj"
+	payload := `{"success": true, "data": {"renderId": "` + renderID + `"}}`
+
+	// sendCallback sends a callback to the handler and returns the status code
+	sendCallback := func(h http.Handler, method string, target string, body string, header http.Header) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k := range header {
+			req.Header.Set(k, header.Get(k))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("Should dispatch the event and download the report", func(t *testing.T) {
+		content := "<xml>File Content</xml>"
+		// ---- httpmock
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("GET", "https://api.carbone.io/render/"+renderID, httpmock.NewBytesResponder(200, []byte(content)))
+		// ----
+		var report []byte
+		h := csdk.NewWebhookHandler("secret", func(ctx context.Context, event WebhookEvent) error {
+			if !event.Success || event.RenderID != renderID {
+				return errors.New("unexpected event")
+			}
+			var err error
+			report, err = event.GetReport(ctx)
+			return err
+		})
+		code := sendCallback(h, "POST", "/webhook", payload, http.Header{DefaultWebhookSecretHeader: {"secret"}})
+		if code != http.StatusOK {
+			t.Fatal(errors.New("The callback should have been accepted"))
+		}
+		if string(report) != content {
+			t.Error(errors.New("The content is not equal"))
+		}
+	})
+
+	t.Run("Should verify the secret of the header", func(t *testing.T) {
+		calls := 0
+		h := csdk.NewWebhookHandler("secret", func(ctx context.Context, event WebhookEvent) error {
+			calls++
+			return nil
+		})
+		h.SecretHeader = "X-Custom-Secret"
+		if sendCallback(h, "POST", "/webhook", payload, http.Header{"X-Custom-Secret": {"wrong"}}) != http.StatusUnauthorized {
+			t.Error(errors.New("A wrong secret should have been rejected"))
+		}
+		if sendCallback(h, "POST", "/webhook", payload, nil) != http.StatusUnauthorized {
+			t.Error(errors.New("A missing secret should have been rejected"))
+		}
+		if sendCallback(h, "POST", "/webhook", payload, http.Header{"X-Custom-Secret": {"secret"}}) != http.StatusOK {
+			t.Error(errors.New("The secret of the header should have been accepted"))
+		}
+		if sendCallback(h, "POST", "/webhook?secret=secret", payload, nil) != http.StatusUnauthorized {
+			t.Error(errors.New("The secret of the query should have been rejected"))
+		}
+		if calls != 1 {
+			t.Error(errors.New("OnRender should have been called for the verified callbacks only"))
+		}
+	})
+
+	t.Run("Should reject a callback refused by Verify", func(t *testing.T) {
+		h := &WebhookHandler{
+			Verify: func(r *http.Request, body []byte) error {
+				if r.Header.Get("X-Signature") != "sig:"+string(body) {
+					return errors.New("invalid signature")
+				}
+				return nil
+			},
+		}
+		if sendCallback(h, "POST", "/webhook", payload, http.Header{"X-Signature": {"sig:other"}}) != http.StatusUnauthorized {
+			t.Error(errors.New("A wrong signature should have been rejected"))
+		}
+		if sendCallback(h, "POST", "/webhook", payload, http.Header{"X-Signature": {"sig:" + payload}}) != http.StatusOK {
+			t.Error(errors.New("A valid signature should have been accepted"))
+		}
+	})
+
+	t.Run("Should dispatch failed renders and reject invalid callbacks", func(t *testing.T) {
+		var failed WebhookEvent
+		h := csdk.NewWebhookHandler("", func(ctx context.Context, event WebhookEvent) error {
+			failed = event
+			return nil
+		})
+		if sendCallback(h, "POST", "/webhook", `{"success": false, "error": "Error while rendering template"}`, nil) != http.StatusOK || failed.Success || failed.Error == "" {
+			t.Error(errors.New("A failed render should have been dispatched"))
+		}
+		if sendCallback(h, "GET", "/webhook", "", nil) != http.StatusMethodNotAllowed {
+			t.Error(errors.New("A GET request should have been rejected"))
+		}
+		if sendCallback(h, "POST", "/webhook", "not json", nil) != http.StatusBadRequest {
+			t.Error(errors.New("An invalid payload should have been rejected"))
+		}
+		if sendCallback(h, "POST", "/webhook", `{"success": true, "data": {}}`, nil) != http.StatusBadRequest {
+			t.Error(errors.New("A payload without renderId should have been rejected"))
+		}
+	})
+
+	t.Run("Should answer an error if OnRender fails", func(t *testing.T) {
+		h := csdk.NewWebhookHandler("", func(ctx context.Context, event WebhookEvent) error {
+			return errors.New("storage unavailable")
+		})
+		if sendCallback(h, "POST", "/webhook", payload, nil) != http.StatusInternalServerError {
+			t.Error(errors.New("The failure of OnRender should have been answered"))
+		}
+	})
+}
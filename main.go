@@ -1,20 +1,51 @@
 package main
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 
 	carbone "github.com/carboneio/carbone-sdk-go/carbone"
 )
 
+type company struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	PostalCode int    `json:"postalCode"`
+}
+
+type product struct {
+	Name       string  `json:"name"`
+	PriceUnit  float64 `json:"priceUnit"`
+	Quantity   int     `json:"quantity"`
+	PriceTotal float64 `json:"priceTotal"`
+}
+
+type invoice struct {
+	ID       int       `json:"id"`
+	Date     int64     `json:"date"`
+	Company  company   `json:"company"`
+	Customer company   `json:"customer"`
+	Products []product `json:"products"`
+	Total    float64   `json:"total"`
+}
+
 func main() {
 	csdk, err := carbone.NewCarboneSDK("secret-token")
 	if err != nil {
 		log.Fatal(err)
 	}
 	templateID := "template"
-	jsonData := `{"data":{"id":42,"date":1492012745,"company":{"name":"myCompany","address":"here","city":"Notfar","postalCode":123456},"customer":{"name":"myCustomer","address":"there","city":"Faraway","postalCode":654321},"products":[{"name":"product 1","priceUnit":0.1,"quantity":10,"priceTotal":1}],"total":140},"convertTo":"pdf"}`
-	reportBuffer, err := csdk.Render(templateID, jsonData, "")
+	data := invoice{
+		ID:       42,
+		Date:     1492012745,
+		Company:  company{Name: "myCompany", Address: "here", City: "Notfar", PostalCode: 123456},
+		Customer: company{Name: "myCustomer", Address: "there", City: "Faraway", PostalCode: 654321},
+		Products: []product{{Name: "product 1", PriceUnit: 0.1, Quantity: 10, PriceTotal: 1}},
+		Total:    140,
+	}
+	reportBuffer, err := carbone.RenderData(context.Background(), csdk, templateID, data, carbone.RenderOptions{ConvertTo: "pdf"})
 	if err != nil {
 		log.Fatal(err)
 	}